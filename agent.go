@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type agent struct {
@@ -35,16 +37,13 @@ type wrappedAgent struct {
 	instanceID uint16
 }
 
-func wrapAgents(agents []agent, events []cbtevent1) map[uint64]*wrappedAgent {
+func wrapAgents(agents []agent) []*wrappedAgent {
 	wrapped := make([]wrappedAgent, len(agents))
-	lookup := make(map[uint64]*wrappedAgent, len(agents))
+	list := make([]*wrappedAgent, len(agents))
 	for i, a := range agents {
 		wrapped[i].agent = a
-		lookup[a.Addr] = &wrapped[i]
-		name := strings.SplitN(string(a.Name[:]), "\x00", 4)
-		wrapped[i].charName = name[0]
-		wrapped[i].acctName = name[1]
-		wrapped[i].subgroup, _ = strconv.Atoi(name[2])
+		list[i] = &wrapped[i]
+		wrapped[i].parseName()
 		wrapped[i].lastAware = ^uint64(0)
 
 		if a.IsElite == 0xffffffff && a.Prof>>16 == 0xffff {
@@ -54,36 +53,100 @@ func wrapAgents(agents []agent, events []cbtevent1) map[uint64]*wrappedAgent {
 		}
 	}
 
-	instanceLookup := make(map[uint16][]*wrappedAgent)
+	return list
+}
 
-	for _, e := range events {
-		if e.IsStateChange == 0 {
-			if a, ok := lookup[e.SrcAgent]; ok {
-				if a.instanceID == 0 {
-					instanceLookup[e.SrcInstID] = append(instanceLookup[e.SrcInstID], a)
-					a.instanceID = e.SrcInstID
-					a.firstAware = e.Time
-				}
+// nameParts splits the raw name field into the character name, account
+// name, and subgroup. Missing parts are empty.
+func (a *wrappedAgent) nameParts() [3]string {
+	var parts [3]string
+	copy(parts[:], strings.SplitN(string(a.Name[:]), "\x00", 4))
+	return parts
+}
+
+// parseName sets the fields parsed from the raw name field.
+func (a *wrappedAgent) parseName() {
+	name := a.nameParts()
+	a.charName = name[0]
+	a.acctName = name[1]
+	a.subgroup, _ = strconv.Atoi(name[2])
+}
+
+// setName replaces one of the null-separated parts of the raw name field
+// and updates the fields parsed from it. The value is shortened if needed
+// so that every part and its null terminator still fits.
+func (a *wrappedAgent) setName(part int, value string) {
+	name := a.nameParts()
+	name[part] = ""
+
+	max := len(a.Name) - len(name)
+	for _, p := range name {
+		max -= len(p)
+	}
+	value = strings.Replace(value, "\x00", "", -1)
+	if max < 0 {
+		max = 0
+	}
+	if len(value) > max {
+		for max > 0 && !utf8.RuneStart(value[max]) {
+			max--
+		}
+		value = value[:max]
+	}
+	name[part] = value
+
+	var raw [64]byte
+	copy(raw[:], strings.Join(name[:], "\x00")+"\x00")
+	a.Name = raw
+	a.parseName()
+}
+
+// agentTracker computes the awareness and master fields of wrappedAgent
+// incrementally as events are read.
+type agentTracker struct {
+	lookup    map[uint64]*wrappedAgent
+	instances map[uint16][]*wrappedAgent
+}
+
+func newAgentTracker(agents []*wrappedAgent) agentTracker {
+	lookup := make(map[uint64]*wrappedAgent, len(agents))
+	for _, a := range agents {
+		lookup[a.Addr] = a
+	}
+
+	return agentTracker{
+		lookup:    lookup,
+		instances: make(map[uint16][]*wrappedAgent),
+	}
+}
 
-				a.lastAware = e.Time
+func (t *agentTracker) update(e cbtevent1) {
+	if e.IsStateChange == 0 {
+		if a, ok := t.lookup[e.SrcAgent]; ok {
+			if a.instanceID == 0 {
+				t.instances[e.SrcInstID] = append(t.instances[e.SrcInstID], a)
+				a.instanceID = e.SrcInstID
+				a.firstAware = e.Time
 			}
+
+			a.lastAware = e.Time
 		}
 	}
 
-	for _, e := range events {
-		if e.SrcMasterInstID != 0 {
-			if a, ok := lookup[e.SrcAgent]; ok {
-				for _, i := range instanceLookup[e.SrcMasterInstID] {
-					if i.firstAware < e.Time && i.lastAware > e.Time {
-						a.masterAddr = i.Addr
-						break
-					}
+	if e.SrcMasterInstID != 0 {
+		if a, ok := t.lookup[e.SrcAgent]; ok {
+			// instance IDs are reused, but only once the previous
+			// agent is gone, so the most recent agent to appear with
+			// this instance ID is the master.
+			candidates := t.instances[e.SrcMasterInstID]
+			for i := len(candidates) - 1; i >= 0; i-- {
+				if candidates[i].firstAware <= e.Time {
+					a.masterAddr = candidates[i].Addr
+					break
 				}
 			}
 		}
 	}
-
-	return lookup
 }
 
 type Agent struct {
@@ -193,14 +256,77 @@ type PlayerInfo struct {
 	Condition     uint8
 }
 
+// Address returns the unique identifier arcdps assigned to this agent.
+func (a *Agent) Address() uint64 {
+	return a.wrapped.Addr
+}
+
 func (a *Agent) Name() string {
 	return a.wrapped.charName
 }
 
+// SetName changes the character name of this agent, for example to
+// anonymize a log before passing it to Encode. Names longer than the agent
+// table allows are truncated.
+func (a *Agent) SetName(name string) {
+	a.wrapped.setName(0, name)
+}
+
+// SetAccount changes the account name of a player agent, in the same form
+// PlayerInfo.Account uses. Names longer than the agent table allows are
+// truncated.
+func (a *Agent) SetAccount(account string) {
+	a.wrapped.setName(1, account)
+}
+
 func (a *Agent) Master() *Agent {
 	return a.chain.agents[a.wrapped.masterAddr]
 }
 
+// Owner follows the chain of masters from this agent to the agent that
+// controls it, such as the player that summoned a minion. It returns a if a
+// has no master, and nil if a is nil.
+func (a *Agent) Owner() *Agent {
+	// a master chain longer than this would be a cycle
+	for i := 0; i < 8 && a != nil && a.Master() != nil; i++ {
+		a = a.Master()
+	}
+	return a
+}
+
+// InstanceID returns the map instance ID of this agent, or 0 if the agent
+// never appeared in a combat event.
+func (a *Agent) InstanceID() uint16 {
+	return a.wrapped.instanceID
+}
+
+// FirstAware returns the local time of the first combat event caused by this
+// agent. It returns the zero time if the agent never caused a combat event.
+func (a *Agent) FirstAware() time.Time {
+	if a.wrapped.instanceID == 0 {
+		return time.Time{}
+	}
+	return a.chain.localTimeAt(a.wrapped.firstAware)
+}
+
+// LastAware returns the local time of the last combat event caused by this
+// agent. It returns the zero time if the agent never caused a combat event.
+func (a *Agent) LastAware() time.Time {
+	if a.wrapped.instanceID == 0 {
+		return time.Time{}
+	}
+	return a.chain.localTimeAt(a.wrapped.lastAware)
+}
+
+// IsAwareAt reports whether t is between FirstAware and LastAware,
+// inclusive.
+func (a *Agent) IsAwareAt(t time.Time) bool {
+	if a.wrapped.instanceID == 0 {
+		return false
+	}
+	return !t.Before(a.FirstAware()) && !t.After(a.LastAware())
+}
+
 func (a *Agent) Hitbox() (width, height int) {
 	return int(a.wrapped.HitboxWidth), int(a.wrapped.HitboxHeight)
 }
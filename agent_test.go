@@ -0,0 +1,65 @@
+package evtc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetName(t *testing.T) {
+	tests := []struct {
+		name, account string
+		wantName      string
+		wantAccount   string
+	}{
+		{"Someone", ":Someone.1234", "Someone", ":Someone.1234"},
+		// the account is set first, while the name is "First Player",
+		// and the subgroup "1" takes one more byte
+		{strings.Repeat("x", 70), ":Someone.1234", strings.Repeat("x", 64-4-len(":Someone.1234")), ":Someone.1234"},
+		{"Someone", strings.Repeat("y", 70), "Someone", strings.Repeat("y", 64-4-len("First Player"))},
+		// names are not cut in the middle of a character
+		{"a" + strings.Repeat("é", 40), "", "a" + strings.Repeat("é", 29), ""},
+		{"No\x00Nulls", ":A.1", "NoNulls", ":A.1"},
+	}
+
+	for _, tt := range tests {
+		chain, err := Parse(bytes.NewReader(writeTestLog(t, 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		a := chain.agents[testPlayer1]
+		a.SetAccount(tt.account)
+		a.SetName(tt.name)
+
+		var buf bytes.Buffer
+		if err := Encode(&buf, chain); err != nil {
+			t.Fatal(err)
+		}
+		chain, err = Parse(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		a = chain.agents[testPlayer1]
+		p, _ := a.Player()
+		if a.Name() != tt.wantName || p.Account != tt.wantAccount || p.Subgroup != 1 {
+			t.Errorf("SetName(%q), SetAccount(%q) gave %q, %q, subgroup %d; want %q, %q, subgroup 1", tt.name, tt.account, a.Name(), p.Account, p.Subgroup, tt.wantName, tt.wantAccount)
+		}
+	}
+}
+
+func TestWrapAgentsShortName(t *testing.T) {
+	var full agent
+	copy(full.Name[:], strings.Repeat("z", len(full.Name)))
+	var one agent
+	copy(one.Name[:], "Name only\x00")
+
+	agents := wrapAgents([]agent{full, one})
+	if got := agents[0].charName; got != strings.Repeat("z", 64) {
+		t.Errorf("unterminated name = %q", got)
+	}
+	if got := agents[1].charName; got != "Name only" || agents[1].acctName != "" || agents[1].subgroup != 0 {
+		t.Errorf("name without account = %q, %q, %d", got, agents[1].acctName, agents[1].subgroup)
+	}
+}
@@ -0,0 +1,182 @@
+// Package breakbar tracks the defiance bars of agents and the crowd control
+// that went into breaking them.
+package breakbar
+
+import (
+	"time"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/series"
+)
+
+// Sample is the state of a defiance bar from Time until the next sample.
+type Sample struct {
+	Time    time.Time
+	State   evtc.BreakbarState
+	Percent float32
+}
+
+// Break is one period during which a defiance bar was active, ending in the
+// bar being broken.
+type Break struct {
+	// Start is when the bar became active. It is the zero time if the
+	// bar was already active when the log started.
+	Start time.Time
+	// End is when the bar was broken.
+	End time.Time
+}
+
+// Duration returns how long the bar took to break. It returns 0 if the start
+// of the bar is not known.
+func (b Break) Duration() time.Duration {
+	if b.Start.IsZero() {
+		return 0
+	}
+	return b.End.Sub(b.Start)
+}
+
+// Timeline is the history of one agent's defiance bar.
+type Timeline struct {
+	Agent *evtc.Agent
+
+	// Samples holds one entry for each time the state or percentage of
+	// the bar changed, in chronological order.
+	Samples []Sample
+
+	// Breaks lists every time the bar was broken.
+	Breaks []Break
+
+	// Contributions is the defiance bar damage dealt by each source
+	// agent. Damage dealt by minions is credited to their master.
+	Contributions map[*evtc.Agent]float64
+
+	activeSince time.Time
+}
+
+// StateAt returns the state of the bar at t. The bar is assumed to be
+// active before the first sample.
+func (tl *Timeline) StateAt(t time.Time) evtc.BreakbarState {
+	return tl.at(t).State
+}
+
+// PercentAt returns the fraction of the bar that remained at t.
+func (tl *Timeline) PercentAt(t time.Time) float32 {
+	return tl.at(t).Percent
+}
+
+func (tl *Timeline) at(t time.Time) Sample {
+	current := Sample{State: evtc.BreakbarActive, Percent: 1}
+	for _, s := range tl.Samples {
+		if s.Time.After(t) {
+			break
+		}
+		current = s
+	}
+	return current
+}
+
+// TotalDamage returns the defiance bar damage dealt by every source.
+func (tl *Timeline) TotalDamage() float64 {
+	var total float64
+	for _, d := range tl.Contributions {
+		total += d
+	}
+	return total
+}
+
+func (tl *Timeline) record(s Sample) {
+	tl.Samples = series.Append(tl.Samples, s, func(s Sample) time.Time { return s.Time })
+}
+
+func (tl *Timeline) last() Sample {
+	if n := len(tl.Samples); n != 0 {
+		return tl.Samples[n-1]
+	}
+	return Sample{State: evtc.BreakbarActive, Percent: 1}
+}
+
+// Tracker builds defiance bar timelines from events. Events must be added in
+// the order they appear in the log.
+type Tracker struct {
+	timelines series.Index[Timeline]
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Track builds the defiance bar timeline of every agent in chain.
+func Track(chain *evtc.EventChain) []*Timeline {
+	t := NewTracker()
+	for _, e := range chain.Events {
+		t.Add(e)
+	}
+	return t.Timelines()
+}
+
+// Timeline returns the defiance bar timeline of an agent, or nil if the
+// agent never had a defiance bar.
+func (t *Tracker) Timeline(a *evtc.Agent) *Timeline {
+	return t.timelines.Get(a)
+}
+
+// Timelines returns every timeline, in the order each agent's defiance bar
+// first appeared.
+func (t *Tracker) Timelines() []*Timeline {
+	return t.timelines.All()
+}
+
+func (t *Tracker) timeline(a *evtc.Agent) *Timeline {
+	return t.timelines.GetOrAdd(a, func() *Timeline {
+		return &Timeline{
+			Agent:         a,
+			Contributions: make(map[*evtc.Agent]float64),
+		}
+	})
+}
+
+// Add updates the timelines with a single event. Events other than
+// defiance bar state, percentage, and damage events are ignored.
+func (t *Tracker) Add(e evtc.Event) {
+	switch e := e.(type) {
+	case *evtc.BreakbarStateEvent:
+		if e.Source == nil {
+			return
+		}
+
+		tl := t.timeline(e.Source)
+		prev := tl.last()
+		s := prev
+		s.Time = e.LocalTime
+		s.State = e.State
+		switch {
+		case e.State == evtc.BreakbarActive && prev.State != evtc.BreakbarActive:
+			tl.activeSince = e.LocalTime
+		case e.State == evtc.BreakbarRecover && prev.State == evtc.BreakbarActive:
+			tl.Breaks = append(tl.Breaks, Break{
+				Start: tl.activeSince,
+				End:   e.LocalTime,
+			})
+			s.Percent = 0
+		}
+		tl.record(s)
+	case *evtc.BreakbarPercentEvent:
+		if e.Source == nil {
+			return
+		}
+
+		tl := t.timeline(e.Source)
+		s := tl.last()
+		s.Time = e.LocalTime
+		s.Percent = e.Percent
+		tl.record(s)
+	case *evtc.BreakbarDamageEvent:
+		if e.Target == nil {
+			return
+		}
+
+		tl := t.timeline(e.Target)
+		tl.Contributions[e.Source.Owner()] += e.Damage
+	}
+}
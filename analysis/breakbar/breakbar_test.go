@@ -0,0 +1,158 @@
+package breakbar
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+const (
+	player = 0x10
+	minion = 0x11
+	other  = 0x12
+	boss   = 0x20
+
+	stun = 1000
+)
+
+func state(l *evtctest.Log, ms uint64, s evtc.BreakbarState) {
+	l.StateChange(ms, 34, boss, 0, int32(s)) // CBTS_BREAKBARSTATE
+}
+
+func percent(l *evtctest.Log, ms uint64, p float32) {
+	l.StateChange(ms, 35, boss, 0, int32(math.Float32bits(p))) // CBTS_BREAKBARPERCENT
+}
+
+func breakbarDamage(l *evtctest.Log, ms uint64, src uint64, damage int32) {
+	l.Damage(ms, src, boss, stun, damage*10, 10) // CBTR_BREAKBAR
+}
+
+func TestTracker(t *testing.T) {
+	type sample struct {
+		state   evtc.BreakbarState
+		percent float32
+	}
+
+	tests := []struct {
+		name  string
+		build func(l *evtctest.Log)
+
+		samples map[uint64]sample
+		// breaks holds the start and end of each break, with 0 for a
+		// start that is not known.
+		breaks        [][2]uint64
+		contributions map[uint64]float64
+	}{
+		{
+			name: "breaks",
+			build: func(l *evtctest.Log) {
+				percent(l, 2000, 0.5)
+				state(l, 3000, evtc.BreakbarRecover)
+				state(l, 5000, evtc.BreakbarActive)
+				percent(l, 5000, 1)
+				percent(l, 6000, 0.25)
+				state(l, 7000, evtc.BreakbarRecover)
+				state(l, 9000, evtc.BreakbarImmune)
+			},
+			samples: map[uint64]sample{
+				1000: {evtc.BreakbarActive, 1},
+				2000: {evtc.BreakbarActive, 0.5},
+				3000: {evtc.BreakbarRecover, 0},
+				5000: {evtc.BreakbarActive, 1},
+				6500: {evtc.BreakbarActive, 0.25},
+				7000: {evtc.BreakbarRecover, 0},
+				9000: {evtc.BreakbarImmune, 0},
+			},
+			breaks: [][2]uint64{{0, 3000}, {5000, 7000}},
+		},
+		{
+			name: "immune bar is not broken",
+			build: func(l *evtctest.Log) {
+				state(l, 2000, evtc.BreakbarImmune)
+				state(l, 3000, evtc.BreakbarRecover)
+			},
+			samples: map[uint64]sample{
+				2000: {evtc.BreakbarImmune, 1},
+				3000: {evtc.BreakbarRecover, 1},
+			},
+		},
+		{
+			name: "contributions",
+			build: func(l *evtctest.Log) {
+				breakbarDamage(l, 2000, player, 50)
+				breakbarDamage(l, 2100, minion, 25)
+				breakbarDamage(l, 2200, other, 10)
+			},
+			contributions: map[uint64]float64{player: 75, other: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &evtctest.Log{}
+			l.Player(player, evtc.Warrior, 0, "Player", ":Player.1234", 1)
+			l.Minion(minion, 8108, "Clone", player)
+			l.Player(other, evtc.Guardian, 0, "Other", ":Other.1234", 1)
+			l.NPC(boss, 15438, "Boss")
+			// the player must act before the minion for the minion to
+			// be linked to it
+			l.Damage(1000, player, boss, stun, 0, 0)
+			tt.build(l)
+			chain := l.Parse(t)
+
+			agents := make(map[uint64]*evtc.Agent)
+			for _, a := range chain.Agents() {
+				agents[a.Address()] = a
+			}
+
+			tracker := NewTracker()
+			for _, e := range chain.Events {
+				tracker.Add(e)
+			}
+			tl := tracker.Timeline(agents[boss])
+			if tl == nil {
+				t.Fatal("no timeline for the boss")
+			}
+
+			for at, want := range tt.samples {
+				got := sample{tl.StateAt(evtctest.At(at)), tl.PercentAt(evtctest.At(at))}
+				if got != want {
+					t.Errorf("at %d: got %v; want %v", at, got, want)
+				}
+			}
+
+			var breaks [][2]uint64
+			for _, b := range tl.Breaks {
+				var start uint64
+				if !b.Start.IsZero() {
+					start = uint64(b.Start.Sub(evtctest.At(0)).Milliseconds())
+				}
+				breaks = append(breaks, [2]uint64{start, uint64(b.End.Sub(evtctest.At(0)).Milliseconds())})
+
+				if b.Start.IsZero() && b.Duration() != 0 {
+					t.Errorf("Duration of a break with an unknown start = %v; want 0", b.Duration())
+				}
+			}
+			if !reflect.DeepEqual(breaks, tt.breaks) {
+				t.Errorf("Breaks = %v; want %v", breaks, tt.breaks)
+			}
+
+			var total float64
+			if len(tl.Contributions) != len(tt.contributions) {
+				t.Errorf("Contributions has %d sources; want %d", len(tl.Contributions), len(tt.contributions))
+			}
+			for addr, want := range tt.contributions {
+				total += want
+				if got := tl.Contributions[agents[addr]]; got != want {
+					t.Errorf("Contributions[%#x] = %v; want %v", addr, got, want)
+				}
+			}
+			if got := tl.TotalDamage(); got != total {
+				t.Errorf("TotalDamage = %v; want %v", got, total)
+			}
+		})
+	}
+}
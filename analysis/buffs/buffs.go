@@ -0,0 +1,386 @@
+// Package buffs replays buff applications and removals to reconstruct the
+// stacks of every buff on every agent over the course of a log.
+package buffs
+
+import (
+	"time"
+
+	"github.com/BenLubar/evtc"
+)
+
+// Sample is the state of a buff on an agent from Time until the next sample.
+type Sample struct {
+	Time time.Time
+
+	// Stacks is the number of stacks in effect. For Duration buffs, this
+	// is at most 1.
+	Stacks int
+
+	// Duration is the total remaining duration of every stack, including
+	// stacks that are waiting their turn.
+	Duration time.Duration
+}
+
+// Timeline is the history of one buff on one agent.
+type Timeline struct {
+	Agent    *evtc.Agent
+	BuffID   int
+	BuffName string
+	Stacking Stacking
+
+	// MaxStacks is the number of Intensity stacks that can be in effect
+	// at once, or 0 if there is no known limit.
+	MaxStacks int
+
+	// Samples holds one entry for each time the state of the buff
+	// changed, in chronological order.
+	Samples []Sample
+
+	// Generation is the amount of time each source agent's stacks were in
+	// effect. Stacks with an unknown source are counted under nil.
+	Generation map[*evtc.Agent]time.Duration
+
+	stacks  []*stack
+	updated time.Time
+}
+
+type stack struct {
+	instance  uint32
+	source    *evtc.Agent
+	remaining time.Duration
+	active    bool
+}
+
+// StacksAt returns the number of stacks in effect at t.
+func (tl *Timeline) StacksAt(t time.Time) int {
+	stacks := 0
+	for _, s := range tl.Samples {
+		if s.Time.After(t) {
+			break
+		}
+		stacks = s.Stacks
+	}
+	return stacks
+}
+
+// Uptime returns the fraction of the time between start and end during which
+// the buff had at least one stack in effect.
+func (tl *Timeline) Uptime(start, end time.Time) float64 {
+	return tl.integrate(start, end, func(s Sample) float64 {
+		if s.Stacks > 0 {
+			return 1
+		}
+		return 0
+	})
+}
+
+// AverageStacks returns the average number of stacks in effect between start
+// and end.
+func (tl *Timeline) AverageStacks(start, end time.Time) float64 {
+	return tl.integrate(start, end, func(s Sample) float64 {
+		return float64(s.Stacks)
+	})
+}
+
+func (tl *Timeline) integrate(start, end time.Time, f func(Sample) float64) float64 {
+	if !end.After(start) {
+		return 0
+	}
+
+	var total float64
+	current := Sample{Time: start}
+	for _, s := range tl.Samples {
+		if !s.Time.After(start) {
+			current = s
+			continue
+		}
+		if !s.Time.Before(end) {
+			break
+		}
+
+		from := current.Time
+		if from.Before(start) {
+			from = start
+		}
+		total += f(current) * float64(s.Time.Sub(from))
+		current = s
+	}
+
+	from := current.Time
+	if from.Before(start) {
+		from = start
+	}
+	total += f(current) * float64(end.Sub(from))
+
+	return total / float64(end.Sub(start))
+}
+
+// effective returns the stacks that are currently counting down.
+func (tl *Timeline) effective() []*stack {
+	if tl.Stacking == Intensity || len(tl.stacks) == 0 {
+		if tl.MaxStacks > 0 && len(tl.stacks) > tl.MaxStacks {
+			return tl.stacks[:tl.MaxStacks]
+		}
+		return tl.stacks
+	}
+
+	for _, s := range tl.stacks {
+		if s.active {
+			return []*stack{s}
+		}
+	}
+
+	// logs without stack IDs never mark a stack active
+	return tl.stacks[:1]
+}
+
+// advance counts down the effective stacks and credits their sources up
+// to now.
+func (tl *Timeline) advance(now time.Time) {
+	if !tl.updated.IsZero() && now.After(tl.updated) {
+		elapsed := now.Sub(tl.updated)
+		for _, s := range tl.effective() {
+			tl.Generation[s.source] += elapsed
+			s.remaining -= elapsed
+			if s.remaining < 0 {
+				s.remaining = 0
+			}
+		}
+	}
+	tl.updated = now
+}
+
+// record appends a sample describing the current state of the buff.
+func (tl *Timeline) record(now time.Time) {
+	sample := Sample{
+		Time:   now,
+		Stacks: len(tl.effective()),
+	}
+	for _, s := range tl.stacks {
+		sample.Duration += s.remaining
+	}
+
+	if n := len(tl.Samples); n != 0 && !tl.Samples[n-1].Time.Before(now) {
+		tl.Samples[n-1] = sample
+		return
+	}
+	tl.Samples = append(tl.Samples, sample)
+}
+
+func (tl *Timeline) find(instance uint32) int {
+	for i, s := range tl.stacks {
+		if s.instance == instance {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tl *Timeline) apply(instance uint32, source *evtc.Agent, duration time.Duration, active bool) {
+	if active {
+		for _, s := range tl.stacks {
+			s.active = false
+		}
+	}
+
+	tl.stacks = append(tl.stacks, &stack{
+		instance:  instance,
+		source:    source,
+		remaining: duration,
+		active:    active,
+	})
+}
+
+func (tl *Timeline) remove(instance uint32, all bool) {
+	if all {
+		tl.stacks = nil
+		return
+	}
+
+	i := 0
+	if instance != 0 {
+		if i = tl.find(instance); i == -1 {
+			// already removed, for example by an earlier
+			// removal of all stacks
+			return
+		}
+	}
+	if i < len(tl.stacks) {
+		tl.stacks = append(tl.stacks[:i], tl.stacks[i+1:]...)
+	}
+}
+
+// Key identifies the timeline of one buff on one agent.
+type Key struct {
+	Agent *evtc.Agent
+	Buff  int
+}
+
+// Tracker builds buff timelines from events. Events must be added in the
+// order they appear in the log.
+type Tracker struct {
+	// Stacking returns the stacking type of a buff. It is consulted
+	// once per buff per agent.
+	Stacking func(buffID int) Stacking
+
+	// MaxStacks returns the stack limit of a buff, or 0 if there is no
+	// known limit. It is optional.
+	MaxStacks func(buffID int) int
+
+	timelines map[Key]*Timeline
+	byAgent   map[*evtc.Agent][]*Timeline
+	order     []*Timeline
+	last      time.Time
+}
+
+// NewTracker returns a Tracker that uses stacking to determine the stacking
+// type of each buff. If stacking is nil, DefaultStacking is used.
+func NewTracker(stacking func(buffID int) Stacking) *Tracker {
+	if stacking == nil {
+		stacking = DefaultStacking
+	}
+
+	return &Tracker{
+		Stacking:  stacking,
+		timelines: make(map[Key]*Timeline),
+		byAgent:   make(map[*evtc.Agent][]*Timeline),
+	}
+}
+
+// Track builds buff timelines from every event in chain. If stacking is nil,
+// ChainStacking is used. Stack limits are taken from the buff definitions in
+// the log, if it has any.
+func Track(chain *evtc.EventChain, stacking func(buffID int) Stacking) *Tracker {
+	if stacking == nil {
+		stacking = ChainStacking(chain)
+	}
+
+	t := NewTracker(stacking)
+	t.MaxStacks = chainMaxStacks(chain)
+	for _, e := range chain.Events {
+		t.Add(e)
+	}
+	t.Finish(t.last)
+	return t
+}
+
+// Finish credits the stacks still in effect at now to their sources. Call it
+// with the time of the last event after adding every event.
+func (t *Tracker) Finish(now time.Time) {
+	for _, tl := range t.order {
+		tl.advance(now)
+	}
+}
+
+// Timeline returns the timeline of a buff on an agent, or nil if the agent
+// never had the buff.
+func (t *Tracker) Timeline(agent *evtc.Agent, buffID int) *Timeline {
+	return t.timelines[Key{agent, buffID}]
+}
+
+// Timelines returns every timeline, in the order the buff first appeared on
+// each agent.
+func (t *Tracker) Timelines() []*Timeline {
+	return append([]*Timeline(nil), t.order...)
+}
+
+func (t *Tracker) timeline(agent *evtc.Agent, buffID int, buffName string) *Timeline {
+	key := Key{agent, buffID}
+	if tl, ok := t.timelines[key]; ok {
+		return tl
+	}
+
+	tl := &Timeline{
+		Agent:      agent,
+		BuffID:     buffID,
+		BuffName:   buffName,
+		Stacking:   t.Stacking(buffID),
+		Generation: make(map[*evtc.Agent]time.Duration),
+	}
+	if t.MaxStacks != nil {
+		tl.MaxStacks = t.MaxStacks(buffID)
+	}
+	t.timelines[key] = tl
+	t.byAgent[agent] = append(t.byAgent[agent], tl)
+	t.order = append(t.order, tl)
+	return tl
+}
+
+// byInstance finds the timeline of the buff an agent has a stack with the
+// given ID of. Stack active and stack reset events do not include the buff.
+func (t *Tracker) byInstance(agent *evtc.Agent, instance uint32) (*Timeline, int) {
+	for _, tl := range t.byAgent[agent] {
+		if i := tl.find(instance); i != -1 {
+			return tl, i
+		}
+	}
+	return nil, -1
+}
+
+// Add updates the timelines with a single event. Events that are not related
+// to buffs are ignored.
+func (t *Tracker) Add(e evtc.Event) {
+	if local, _ := e.Time(); local.After(t.last) {
+		t.last = local
+	}
+
+	switch e := e.(type) {
+	case *evtc.ApplyBuffEvent:
+		if e.Target == nil {
+			return
+		}
+		tl := t.timeline(e.Target, e.SkillID, e.SkillName)
+		tl.advance(e.LocalTime)
+		if i := tl.find(e.Instance); e.NewDuration != 0 && e.Instance != 0 && i != -1 {
+			// an existing stack was extended
+			tl.stacks[i].remaining = e.NewDuration
+		} else {
+			tl.apply(e.Instance, e.Source, e.Duration, e.Active)
+		}
+		tl.record(e.LocalTime)
+	case *evtc.InitialBuffEvent:
+		agent := e.Target
+		if agent == nil {
+			agent = e.Source
+		}
+		if agent == nil {
+			return
+		}
+		tl := t.timeline(agent, e.SkillID, e.SkillName)
+		tl.advance(e.LocalTime)
+		tl.apply(e.Instance, e.Source, e.Duration, e.Active)
+		tl.record(e.LocalTime)
+	case *evtc.BuffRemoveEvent:
+		// Synthesized removals are generated by arcdps rather than the
+		// server, but they still mean the stack is gone.
+		if e.Target == nil {
+			return
+		}
+		tl := t.Timeline(e.Target, e.SkillID)
+		if tl == nil {
+			return
+		}
+		tl.advance(e.LocalTime)
+		tl.remove(e.Instance, e.All)
+		tl.record(e.LocalTime)
+	case *evtc.BuffActiveEvent:
+		tl, i := t.byInstance(e.Source, e.Instance)
+		if tl == nil {
+			return
+		}
+		tl.advance(e.LocalTime)
+		for j, s := range tl.stacks {
+			s.active = j == i
+		}
+		tl.record(e.LocalTime)
+	case *evtc.BuffResetEvent:
+		tl, i := t.byInstance(e.Source, e.Instance)
+		if tl == nil {
+			return
+		}
+		tl.advance(e.LocalTime)
+		tl.stacks[i].remaining = e.Duration
+		tl.stacks[i].active = false
+		tl.record(e.LocalTime)
+	}
+}
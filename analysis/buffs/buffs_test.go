@@ -0,0 +1,182 @@
+package buffs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+const (
+	giver  = 0x10
+	target = 0x20
+	other  = 0x30
+
+	might     = 740
+	quickness = 1187
+)
+
+func newLog() *evtctest.Log {
+	l := &evtctest.Log{}
+	l.Player(giver, evtc.Guardian, 0, "Giver", ":Giver.1234", 1)
+	l.Player(target, evtc.Warrior, 0, "Target", ":Target.1234", 1)
+	l.Player(other, evtc.Mesmer, 0, "Other", ":Other.1234", 1)
+	l.Skill(might, "Might")
+	l.Skill(quickness, "Quickness")
+	return l
+}
+
+func TestTracker(t *testing.T) {
+	ms := time.Millisecond
+
+	tests := []struct {
+		name  string
+		buff  int
+		build func(l *evtctest.Log)
+		end   uint64
+
+		stacks     map[uint64]int
+		generation map[uint64]time.Duration
+		uptime     float64
+	}{
+		{
+			name: "intensity stacks count down together",
+			buff: might,
+			build: func(l *evtctest.Log) {
+				l.ApplyBuff(1000, giver, target, might, 3000*ms, 1, false)
+				l.ApplyBuff(2000, other, target, might, 1000*ms, 2, false)
+				l.RemoveBuff(3000, 0, target, might, 3, 2)
+				l.RemoveBuff(4000, 0, target, might, 3, 1)
+			},
+			end:        5000,
+			stacks:     map[uint64]int{500: 0, 1000: 1, 2500: 2, 3000: 1, 4000: 0},
+			generation: map[uint64]time.Duration{giver: 3000 * ms, other: 1000 * ms},
+			uptime:     0.6,
+		},
+		{
+			name: "duration stacks take turns",
+			buff: quickness,
+			build: func(l *evtctest.Log) {
+				l.ApplyBuff(1000, giver, target, quickness, 2000*ms, 1, true)
+				l.ApplyBuff(1500, other, target, quickness, 2000*ms, 2, false)
+				l.RemoveBuff(3000, 0, target, quickness, 3, 1)
+				l.StateChange(3000, 27, target, 2, 0) // CBTS_STACKACTIVE
+				l.RemoveBuff(5000, 0, target, quickness, 3, 2)
+			},
+			end:        5000,
+			stacks:     map[uint64]int{1000: 1, 1500: 1, 3000: 1, 5000: 0},
+			generation: map[uint64]time.Duration{giver: 2000 * ms, other: 2000 * ms},
+			uptime:     0.8,
+		},
+		{
+			name: "stack reset",
+			buff: quickness,
+			build: func(l *evtctest.Log) {
+				l.ApplyBuff(1000, giver, target, quickness, 1000*ms, 1, true)
+				l.ApplyBuff(1000, other, target, quickness, 3000*ms, 2, false)
+				// the first stack is paused with 500ms left and the
+				// second one takes over
+				l.Add(evtctest.Record{Time: 1500, IsStateChange: 28, SrcAgent: target, Value: 500, Pad61_64: 1})
+				l.StateChange(1500, 27, target, 2, 0)
+				l.RemoveBuff(4500, 0, target, quickness, 3, 2)
+				l.StateChange(4500, 27, target, 1, 0)
+				l.RemoveBuff(5000, 0, target, quickness, 1, 0)
+			},
+			end:        5000,
+			stacks:     map[uint64]int{1000: 1, 4500: 1, 5000: 0},
+			generation: map[uint64]time.Duration{giver: 1000 * ms, other: 3000 * ms},
+			uptime:     0.8,
+		},
+		{
+			name: "removing all stacks",
+			buff: might,
+			build: func(l *evtctest.Log) {
+				l.ApplyBuff(1000, giver, target, might, 5000*ms, 1, false)
+				l.ApplyBuff(1000, giver, target, might, 5000*ms, 2, false)
+				l.RemoveBuff(2000, other, target, might, 1, 0)
+				// arcdps follows a removal of all stacks with a
+				// removal of each stack
+				l.RemoveBuff(2000, other, target, might, 2, 1)
+				l.RemoveBuff(2000, other, target, might, 2, 2)
+			},
+			end:        4000,
+			stacks:     map[uint64]int{1000: 2, 2000: 0},
+			generation: map[uint64]time.Duration{giver: 2000 * ms},
+			uptime:     0.25,
+		},
+		{
+			name: "stack limit from buff definition",
+			buff: might,
+			build: func(l *evtctest.Log) {
+				// CBTS_BUFFINFO: 2 stacks of intensity
+				l.Add(evtctest.Record{Time: 1000, IsStateChange: 30, SkillID: might, SrcMasterInstID: 2, Pad61_64: 0x04})
+				l.ApplyBuff(1000, giver, target, might, 1000*ms, 1, false)
+				l.ApplyBuff(1000, other, target, might, 1000*ms, 2, false)
+				l.ApplyBuff(1000, giver, target, might, 2000*ms, 3, false)
+				l.RemoveBuff(2000, 0, target, might, 3, 1)
+				l.RemoveBuff(2000, 0, target, might, 3, 2)
+				l.RemoveBuff(4000, 0, target, might, 3, 3)
+			},
+			end:        4000,
+			stacks:     map[uint64]int{1000: 2, 2000: 1, 4000: 0},
+			generation: map[uint64]time.Duration{giver: 3000 * ms, other: 1000 * ms},
+			uptime:     0.75,
+		},
+		{
+			name: "extended stack",
+			buff: quickness,
+			build: func(l *evtctest.Log) {
+				l.ApplyBuff(1000, giver, target, quickness, 1000*ms, 1, true)
+				// is_offcycle marks overstack_value as the new
+				// duration of an existing stack
+				l.Add(evtctest.Record{Time: 1500, SrcAgent: other, DstAgent: target, SkillID: quickness, Buff: 1, Value: 1500, OverstackValue: 2000, IsOffCycle: 1, Pad61_64: 1})
+				l.RemoveBuff(3500, 0, target, quickness, 3, 1)
+			},
+			end:        4000,
+			stacks:     map[uint64]int{1000: 1, 3500: 0},
+			generation: map[uint64]time.Duration{giver: 2500 * ms},
+			uptime:     0.625,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLog()
+			tt.build(l)
+			chain := l.Parse(t)
+
+			tracker := Track(chain, nil)
+			tracker.Finish(evtctest.At(tt.end))
+
+			agents := make(map[uint64]*evtc.Agent)
+			for _, a := range chain.Agents() {
+				agents[a.Address()] = a
+			}
+
+			tl := tracker.Timeline(agents[target], tt.buff)
+			if tl == nil {
+				t.Fatalf("no timeline for buff %d", tt.buff)
+			}
+
+			for at, want := range tt.stacks {
+				if got := tl.StacksAt(evtctest.At(at)); got != want {
+					t.Errorf("StacksAt(%d) = %d; want %d", at, got, want)
+				}
+			}
+
+			if len(tl.Generation) != len(tt.generation) {
+				t.Errorf("Generation has %d sources; want %d", len(tl.Generation), len(tt.generation))
+			}
+			for addr, want := range tt.generation {
+				if got := tl.Generation[agents[addr]]; got != want {
+					t.Errorf("Generation[%#x] = %v; want %v", addr, got, want)
+				}
+			}
+
+			if got := tl.Uptime(evtctest.At(0), evtctest.At(tt.end)); got != tt.uptime {
+				t.Errorf("Uptime = %v; want %v", got, tt.uptime)
+			}
+		})
+	}
+}
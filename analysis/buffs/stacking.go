@@ -0,0 +1,94 @@
+package buffs
+
+import (
+	"strconv"
+
+	"github.com/BenLubar/evtc"
+)
+
+// Stacking is how multiple stacks of a buff combine.
+type Stacking int
+
+const (
+	// Intensity buffs, such as might or bleeding, get stronger with each
+	// stack, and every stack counts down at the same time.
+	Intensity Stacking = iota
+	// Duration buffs, such as quickness or fury, have one active stack
+	// at a time. The other stacks wait their turn.
+	Duration
+)
+
+func (s Stacking) String() string {
+	switch s {
+	case Intensity:
+		return "Intensity"
+	case Duration:
+		return "Duration"
+	default:
+		return strconv.Itoa(int(s))
+	}
+}
+
+// defaultStacking lists the stacking type of boons and conditions.
+var defaultStacking = map[int]Stacking{
+	// boons
+	740:   Intensity, // Might
+	1122:  Intensity, // Stability
+	725:   Duration,  // Fury
+	1187:  Duration,  // Quickness
+	30328: Duration,  // Alacrity
+	717:   Duration,  // Protection
+	718:   Duration,  // Regeneration
+	719:   Duration,  // Swiftness
+	726:   Duration,  // Vigor
+	743:   Duration,  // Aegis
+	873:   Duration,  // Resolution
+	26980: Duration,  // Resistance
+	5974:  Duration,  // Superspeed
+
+	// conditions
+	736:   Intensity, // Bleeding
+	737:   Intensity, // Burning
+	723:   Intensity, // Poison
+	738:   Intensity, // Vulnerability
+	861:   Intensity, // Confusion
+	19426: Intensity, // Torment
+	720:   Duration,  // Blinded
+	721:   Duration,  // Crippled
+	722:   Duration,  // Chilled
+	727:   Duration,  // Immobile
+	742:   Duration,  // Weakness
+	791:   Duration,  // Fear
+	26766: Duration,  // Slow
+	27705: Duration,  // Taunt
+}
+
+// DefaultStacking returns the stacking type of boons and conditions. Other
+// buffs are assumed to stack in intensity.
+func DefaultStacking(buffID int) Stacking {
+	return defaultStacking[buffID]
+}
+
+// ChainStacking returns a stacking function that uses the buff definitions
+// recorded in chain, falling back to DefaultStacking for buffs the log does
+// not describe.
+func ChainStacking(chain *evtc.EventChain) func(buffID int) Stacking {
+	buffs := chain.Buffs()
+	return func(buffID int) Stacking {
+		info, ok := buffs[buffID]
+		if !ok {
+			return DefaultStacking(buffID)
+		}
+		if info.StackType.Intensity() {
+			return Intensity
+		}
+		return Duration
+	}
+}
+
+func chainMaxStacks(chain *evtc.EventChain) func(buffID int) int {
+	buffs := chain.Buffs()
+	return func(buffID int) int {
+		return buffs[buffID].MaxStacks
+	}
+}
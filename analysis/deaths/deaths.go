@@ -0,0 +1,145 @@
+// Package deaths builds death recaps: what happened to a player in the
+// seconds before they went down or died.
+package deaths
+
+import (
+	"sort"
+	"time"
+
+	"github.com/BenLubar/evtc"
+)
+
+// DefaultWindow is how far back a recap looks if no window is given.
+const DefaultWindow = 10 * time.Second
+
+// Hit is incoming damage.
+type Hit struct {
+	Time      time.Time
+	Source    *evtc.Agent
+	SkillID   int
+	SkillName string
+	Damage    int
+	// Condition is true for damage from buffs, such as bleeding.
+	Condition bool
+	Critical  bool
+
+	Event evtc.Event `json:"-"`
+}
+
+// Health is a health update, as a percentage of maximum health.
+type Health struct {
+	Time    time.Time
+	Percent float64
+}
+
+// Recap describes what happened to a player before they went down or died.
+type Recap struct {
+	Agent *evtc.Agent
+	Time  time.Time
+	// Downed is true if the player went down, and false if they died.
+	Downed bool
+
+	// Hits is the damage the player took during the window, oldest first.
+	Hits []Hit
+	// Health is the player's health updates during the window, oldest
+	// first.
+	Health []Health
+	// KillingBlow is the hit that downed or killed the player, if there
+	// was one.
+	KillingBlow *Hit `json:",omitempty"`
+}
+
+// DeathRecaps returns a recap for each time a player went down or died in
+// chain, in the order they happened. Each recap covers the window before the
+// death; if window is 0, DefaultWindow is used.
+func DeathRecaps(chain *evtc.EventChain, window time.Duration) []Recap {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	type history struct {
+		hits   []Hit
+		health []Health
+	}
+	histories := make(map[*evtc.Agent]*history)
+	for _, a := range chain.Players() {
+		histories[a] = &history{}
+	}
+
+	var recaps []Recap
+	for _, e := range chain.Events {
+		switch e := e.(type) {
+		case *evtc.DirectDamageEvent:
+			if h := histories[e.Target]; h != nil && (e.Damage != 0 || e.BecameDowned || e.BecameDefeated) {
+				h.hits = append(h.hits, Hit{
+					Time:      e.LocalTime,
+					Source:    e.Source,
+					SkillID:   e.SkillID,
+					SkillName: e.SkillName,
+					Damage:    e.Damage,
+					Critical:  e.Critical,
+					Event:     e,
+				})
+			}
+		case *evtc.BuffDamageEvent:
+			if h := histories[e.Target]; h != nil && e.Success && e.Damage != 0 {
+				h.hits = append(h.hits, Hit{
+					Time:      e.LocalTime,
+					Source:    e.Source,
+					SkillID:   e.SkillID,
+					SkillName: e.SkillName,
+					Damage:    e.Damage,
+					Condition: true,
+					Event:     e,
+				})
+			}
+		case *evtc.HealthUpdateEvent:
+			if h := histories[e.Source]; h != nil {
+				h.health = append(h.health, Health{
+					Time:    e.LocalTime,
+					Percent: float64(e.Percentage) / 100,
+				})
+			}
+		case *evtc.StateChangedEvent:
+			if histories[e.Source] != nil && (e.Downed || e.Defeated) {
+				recaps = append(recaps, Recap{
+					Agent:  e.Source,
+					Time:   e.LocalTime,
+					Downed: e.Downed,
+				})
+			}
+		}
+	}
+
+	// arcdps does not always log events in order
+	for _, h := range histories {
+		sort.SliceStable(h.hits, func(i, j int) bool { return h.hits[i].Time.Before(h.hits[j].Time) })
+		sort.SliceStable(h.health, func(i, j int) bool { return h.health[i].Time.Before(h.health[j].Time) })
+	}
+
+	for i := range recaps {
+		r := &recaps[i]
+		h := histories[r.Agent]
+		from := r.Time.Add(-window)
+
+		// the killing blow can be logged just after the state change,
+		// so include everything up to the same millisecond.
+		hits := h.hits[sort.Search(len(h.hits), func(j int) bool { return !h.hits[j].Time.Before(from) }):]
+		hits = hits[:sort.Search(len(hits), func(j int) bool { return hits[j].Time.After(r.Time) })]
+		r.Hits = append([]Hit(nil), hits...)
+
+		health := h.health[sort.Search(len(h.health), func(j int) bool { return !h.health[j].Time.Before(from) }):]
+		health = health[:sort.Search(len(health), func(j int) bool { return health[j].Time.After(r.Time) })]
+		r.Health = append([]Health(nil), health...)
+
+		for j := len(r.Hits) - 1; j >= 0; j-- {
+			d, ok := r.Hits[j].Event.(*evtc.DirectDamageEvent)
+			if ok && ((r.Downed && d.BecameDowned) || (!r.Downed && d.BecameDefeated)) {
+				r.KillingBlow = &r.Hits[j]
+				break
+			}
+		}
+	}
+
+	return recaps
+}
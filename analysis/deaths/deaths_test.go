@@ -0,0 +1,145 @@
+package deaths
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+const (
+	player = 0x10
+	ally   = 0x11
+	boss   = 0x20
+
+	smash    = 1000
+	bleeding = 736
+)
+
+// noKillingBlow is the killing blow of a recap that has none.
+const noKillingBlow = -1
+
+type recap struct {
+	at     uint64
+	agent  uint64
+	downed bool
+	// hits is the damage of each hit in the recap.
+	hits   []int
+	health []float64
+	// killingBlow is the index of the killing blow in hits, or
+	// noKillingBlow.
+	killingBlow int
+}
+
+func TestDeathRecaps(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		build  func(l *evtctest.Log)
+		want   []recap
+	}{
+		{
+			name:   "downed then killed",
+			window: 5 * time.Second,
+			build: func(l *evtctest.Log) {
+				l.Damage(1000, boss, player, smash, 5000, 0)
+				l.StateChange(1000, 8, player, 8000, 0) // CBTS_HEALTHUPDATE
+				l.Damage(7000, boss, player, smash, 6000, 1)
+				l.StateChange(7000, 8, player, 1000, 0)
+				l.Damage(8000, boss, player, smash, 2000, 9) // CBTR_DOWNED
+				l.StateChange(8000, 5, player, 0, 0)         // CBTS_CHANGEDOWN
+				l.Damage(9000, boss, player, smash, 3000, 8) // CBTR_KILLINGBLOW
+				l.StateChange(9000, 4, player, 0, 0)         // CBTS_CHANGEDEAD
+			},
+			want: []recap{
+				{at: 8000, agent: player, downed: true, hits: []int{6000, 2000}, health: []float64{10}, killingBlow: 1},
+				{at: 9000, agent: player, hits: []int{6000, 2000, 3000}, health: []float64{10}, killingBlow: 2},
+			},
+		},
+		{
+			name: "killing blow logged after the state change",
+			build: func(l *evtctest.Log) {
+				l.Damage(2000, boss, player, smash, 1000, 0)
+				l.StateChange(3000, 5, player, 0, 0)
+				l.Damage(3000, boss, player, smash, 4000, 9)
+			},
+			want: []recap{
+				{at: 3000, agent: player, downed: true, hits: []int{1000, 4000}, killingBlow: 1},
+			},
+		},
+		{
+			name: "conditions and misses",
+			build: func(l *evtctest.Log) {
+				l.Damage(2000, boss, player, smash, 0, 4) // evaded
+				l.ConditionDamage(2500, boss, player, bleeding, 300)
+				l.Add(evtctest.Record{Time: 2600, SrcAgent: boss, DstAgent: player, SkillID: bleeding, BuffDmg: 300, Buff: 1, Result: 1})
+				l.ConditionDamage(3000, boss, player, bleeding, 300)
+				l.StateChange(3000, 4, player, 0, 0)
+			},
+			want: []recap{
+				{at: 3000, agent: player, hits: []int{300, 300}, killingBlow: noKillingBlow},
+			},
+		},
+		{
+			name: "each player has their own history",
+			build: func(l *evtctest.Log) {
+				l.Damage(2000, boss, ally, smash, 1000, 0)
+				l.Damage(2500, boss, player, smash, 2000, 0)
+				l.StateChange(2500, 8, ally, 5000, 0)
+				l.StateChange(3000, 4, ally, 0, 0)
+				l.StateChange(3000, 4, boss, 0, 0)
+			},
+			want: []recap{
+				{at: 3000, agent: ally, hits: []int{1000}, health: []float64{50}, killingBlow: noKillingBlow},
+			},
+		},
+		{
+			name: "default window",
+			build: func(l *evtctest.Log) {
+				l.Damage(1000, boss, player, smash, 1000, 0)
+				l.Damage(2000, boss, player, smash, 2000, 0)
+				l.StateChange(12000, 4, player, 0, 0)
+			},
+			want: []recap{
+				{at: 12000, agent: player, hits: []int{2000}, killingBlow: noKillingBlow},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &evtctest.Log{}
+			l.Player(player, evtc.Warrior, 0, "Player", ":Player.1234", 1)
+			l.Player(ally, evtc.Guardian, 0, "Ally", ":Ally.1234", 1)
+			l.NPC(boss, 15438, "Boss")
+			tt.build(l)
+			chain := l.Parse(t)
+
+			var got []recap
+			for _, r := range DeathRecaps(chain, tt.window) {
+				g := recap{
+					at:          uint64(r.Time.Sub(evtctest.At(0)).Milliseconds()),
+					agent:       r.Agent.Address(),
+					downed:      r.Downed,
+					killingBlow: noKillingBlow,
+				}
+				for i := range r.Hits {
+					g.hits = append(g.hits, r.Hits[i].Damage)
+					if &r.Hits[i] == r.KillingBlow {
+						g.killingBlow = i
+					}
+				}
+				for _, h := range r.Health {
+					g.health = append(g.health, h.Percent)
+				}
+				got = append(got, g)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DeathRecaps =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
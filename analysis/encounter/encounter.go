@@ -0,0 +1,71 @@
+// Package encounter describes the boss encounters recorded by arcdps: how
+// they split into phases, whether they succeeded, and which bosses take part
+// in them.
+package encounter
+
+import (
+	"time"
+
+	"github.com/BenLubar/evtc"
+)
+
+// bounds returns the time of the first and last events in chain.
+func bounds(chain *evtc.EventChain) (start, end time.Time) {
+	start = chain.Start()
+	for _, e := range chain.Events {
+		local, _ := e.Time()
+		if start.IsZero() {
+			start = local
+		}
+		if local.After(end) {
+			end = local
+		}
+	}
+	return
+}
+
+// Targets returns the boss agents of the encounter in chain. For encounters
+// with several bosses, such as the Bandit Trio, this includes every boss.
+func Targets(chain *evtc.EventChain) []*evtc.Agent {
+	ids := []int{chain.BossSpecies}
+	if e, ok := Identify(chain); ok {
+		ids = e.Targets
+	}
+
+	var targets []*evtc.Agent
+	for _, a := range chain.NPCs() {
+		n, _ := a.NPC()
+		for _, id := range ids {
+			if n.SpeciesID == id {
+				targets = append(targets, a)
+				break
+			}
+		}
+	}
+	return targets
+}
+
+func isTarget(targets []*evtc.Agent, a *evtc.Agent) bool {
+	if a == nil {
+		return false
+	}
+	for _, t := range targets {
+		if t == a {
+			return true
+		}
+	}
+	return false
+}
+
+// attackTargets maps the attack target gadgets of the targets to the targets
+// themselves. arcdps records targetable state changes on the gadget, which
+// is linked to its target by a WeakPointEvent.
+func attackTargets(chain *evtc.EventChain, targets []*evtc.Agent) map[*evtc.Agent]*evtc.Agent {
+	m := make(map[*evtc.Agent]*evtc.Agent)
+	for _, e := range chain.Events {
+		if w, ok := e.(*evtc.WeakPointEvent); ok && w.Source != nil && isTarget(targets, w.Boss) {
+			m[w.Source] = w.Boss
+		}
+	}
+	return m
+}
@@ -0,0 +1,190 @@
+package encounter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/BenLubar/evtc"
+)
+
+// An OutcomeFunc decides whether an encounter succeeded. If it did, at is
+// the time the encounter was won.
+type OutcomeFunc func(chain *evtc.EventChain, targets []*evtc.Agent) (success bool, at time.Time)
+
+var (
+	outcomeMu    sync.RWMutex
+	outcomeFuncs = map[int]OutcomeFunc{
+		16246: Despawned(3),     // Xera teleports away instead of dying
+		19828: Escorted(),       // Desmina must survive crossing the river
+		17154: Untargetable(10), // Deimos becomes untargetable at the end
+	}
+)
+
+// RegisterOutcome sets the OutcomeFunc used for encounters with the given
+// boss species, replacing any earlier registration. A nil OutcomeFunc
+// removes the registration.
+func RegisterOutcome(species int, f OutcomeFunc) {
+	outcomeMu.Lock()
+	defer outcomeMu.Unlock()
+
+	if f == nil {
+		delete(outcomeFuncs, species)
+	} else {
+		outcomeFuncs[species] = f
+	}
+}
+
+// Outcome reports whether the encounter in chain succeeded, along with the
+// time from the start of the log until the boss was defeated. For failed
+// encounters, duration is the length of the log.
+//
+// The OutcomeFunc registered for chain.BossSpecies decides the outcome. If
+// there is none, DefaultOutcome is used.
+func Outcome(chain *evtc.EventChain) (success bool, duration time.Duration) {
+	start, end := bounds(chain)
+	targets := Targets(chain)
+
+	outcomeMu.RLock()
+	f := outcomeFuncs[chain.BossSpecies]
+	outcomeMu.RUnlock()
+
+	if f == nil {
+		f = DefaultOutcome
+	}
+
+	success, at := f(chain, targets)
+	if !success {
+		return false, end.Sub(start)
+	}
+	return true, at.Sub(start)
+}
+
+// DefaultOutcome is a success once every target has died, either by a
+// defeated state change or by a final health update of 0%. The time of the
+// last death is the time of the success. If the targets did not all die, a
+// reward chest is also counted as a success.
+func DefaultOutcome(chain *evtc.EventChain, targets []*evtc.Agent) (success bool, at time.Time) {
+	defeated := make(map[*evtc.Agent]time.Time)
+	lastHealth := make(map[*evtc.Agent]*evtc.HealthUpdateEvent)
+	var reward time.Time
+
+	for _, e := range chain.Events {
+		switch e := e.(type) {
+		case *evtc.StateChangedEvent:
+			if e.Defeated && isTarget(targets, e.Source) {
+				if _, ok := defeated[e.Source]; !ok {
+					defeated[e.Source] = e.LocalTime
+				}
+			}
+		case *evtc.HealthUpdateEvent:
+			if isTarget(targets, e.Source) {
+				lastHealth[e.Source] = e
+			}
+		case *evtc.RewardEvent:
+			if reward.IsZero() {
+				reward = e.LocalTime
+			}
+		}
+	}
+
+	for _, t := range targets {
+		d, ok := defeated[t]
+		if !ok {
+			if h := lastHealth[t]; h != nil && h.Percentage == 0 {
+				d, ok = h.LocalTime, true
+			}
+		}
+		if !ok {
+			return !reward.IsZero(), reward
+		}
+		if d.After(at) {
+			at = d
+		}
+	}
+
+	if len(targets) == 0 {
+		return !reward.IsZero(), reward
+	}
+	return true, at
+}
+
+// Despawned returns an OutcomeFunc for bosses that leave instead of dying.
+// The encounter succeeds when a target despawns at or below the given
+// health percentage.
+func Despawned(maxHealth float64) OutcomeFunc {
+	return func(chain *evtc.EventChain, targets []*evtc.Agent) (bool, time.Time) {
+		return whenBelow(chain, targets, maxHealth, func(e evtc.Event) *evtc.Agent {
+			if t, ok := e.(*evtc.TrackingChangedEvent); ok && !t.Tracking {
+				return t.Source
+			}
+			return nil
+		})
+	}
+}
+
+// Untargetable returns an OutcomeFunc for bosses that become untargetable
+// instead of dying. The encounter succeeds when the attack target gadget of
+// a target becomes untargetable while the target is at or below the given
+// health percentage.
+func Untargetable(maxHealth float64) OutcomeFunc {
+	return func(chain *evtc.EventChain, targets []*evtc.Agent) (bool, time.Time) {
+		links := attackTargets(chain, targets)
+		return whenBelow(chain, targets, maxHealth, func(e evtc.Event) *evtc.Agent {
+			if t, ok := e.(*evtc.TargetableEvent); ok && !t.Targetable {
+				return links[t.Source]
+			}
+			return nil
+		})
+	}
+}
+
+// Escorted returns an OutcomeFunc for encounters in which the targets must
+// be kept alive, such as Desmina in the River of Souls. The encounter fails
+// as soon as a target dies, and succeeds when a target despawns at the end
+// of its route or a reward chest is given.
+func Escorted() OutcomeFunc {
+	return func(chain *evtc.EventChain, targets []*evtc.Agent) (bool, time.Time) {
+		for _, e := range chain.Events {
+			switch e := e.(type) {
+			case *evtc.StateChangedEvent:
+				if e.Defeated && isTarget(targets, e.Source) {
+					return false, time.Time{}
+				}
+			case *evtc.HealthUpdateEvent:
+				if e.Percentage == 0 && isTarget(targets, e.Source) {
+					return false, time.Time{}
+				}
+			case *evtc.TrackingChangedEvent:
+				if !e.Tracking && isTarget(targets, e.Source) {
+					return true, e.LocalTime
+				}
+			case *evtc.RewardEvent:
+				return true, e.LocalTime
+			}
+		}
+
+		return false, time.Time{}
+	}
+}
+
+// whenBelow returns the time of the first event that match maps to a target
+// whose health is at or below maxHealth.
+func whenBelow(chain *evtc.EventChain, targets []*evtc.Agent, maxHealth float64, match func(evtc.Event) *evtc.Agent) (bool, time.Time) {
+	health := make(map[*evtc.Agent]float64)
+	for _, t := range targets {
+		health[t] = 100
+	}
+
+	for _, e := range chain.Events {
+		if h, ok := e.(*evtc.HealthUpdateEvent); ok && isTarget(targets, h.Source) {
+			health[h.Source] = float64(h.Percentage) / 100
+			continue
+		}
+		if t := match(e); isTarget(targets, t) && health[t] <= maxHealth {
+			local, _ := e.Time()
+			return true, local
+		}
+	}
+
+	return false, time.Time{}
+}
@@ -0,0 +1,135 @@
+package encounter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+const (
+	xera          = 16246
+	deimos        = 17154
+	riverOfSouls  = 19828
+	unregistered  = 12345
+	logDuration   = 7000 * time.Millisecond
+	failedOutcome = -1
+)
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		name    string
+		species uint16
+		build   func(l *evtctest.Log)
+		// want is the time of the success, in milliseconds, or
+		// failedOutcome.
+		want int
+	}{
+		{
+			name:    "boss defeated",
+			species: valeGuardian,
+			build: func(l *evtctest.Log) {
+				l.StateChange(6000, 4, boss, 0, 0) // CBTS_CHANGEDEAD
+			},
+			want: 6000,
+		},
+		{
+			name:    "boss health reached zero",
+			species: valeGuardian,
+			build: func(l *evtctest.Log) {
+				l.StateChange(5000, 8, boss, 0, 0) // CBTS_HEALTHUPDATE
+			},
+			want: 5000,
+		},
+		{
+			name:    "reward without a death",
+			species: unregistered,
+			build: func(l *evtctest.Log) {
+				l.StateChange(7000, 17, player, 55, 0) // CBTS_REWARD
+			},
+			want: 7000,
+		},
+		{
+			name:    "boss survived",
+			species: valeGuardian,
+			build: func(l *evtctest.Log) {
+				l.StateChange(5000, 8, boss, 1000, 0)
+			},
+			want: failedOutcome,
+		},
+		{
+			name:    "Desmina reached the end",
+			species: riverOfSouls,
+			build: func(l *evtctest.Log) {
+				l.StateChange(6000, 7, boss, 0, 0) // CBTS_DESPAWN
+			},
+			want: 6000,
+		},
+		{
+			name:    "Desmina died",
+			species: riverOfSouls,
+			build: func(l *evtctest.Log) {
+				l.StateChange(5000, 4, boss, 0, 0)
+				l.StateChange(6000, 7, boss, 0, 0)
+			},
+			want: failedOutcome,
+		},
+		{
+			name:    "Xera teleported away",
+			species: xera,
+			build: func(l *evtctest.Log) {
+				l.StateChange(5000, 8, boss, 250, 0)
+				l.StateChange(6000, 7, boss, 0, 0)
+			},
+			want: 6000,
+		},
+		{
+			name:    "Xera despawned early",
+			species: xera,
+			build: func(l *evtctest.Log) {
+				l.StateChange(5000, 8, boss, 5000, 0)
+				l.StateChange(6000, 7, boss, 0, 0)
+			},
+			want: failedOutcome,
+		},
+		{
+			name:    "Deimos became untargetable",
+			species: deimos,
+			build: func(l *evtctest.Log) {
+				l.StateChange(1000, 23, gadget, boss, 1) // CBTS_ATTACKTARGET
+				l.StateChange(4000, 8, boss, 900, 0)
+				l.StateChange(6000, 24, gadget, 0, 0) // CBTS_TARGETABLE
+			},
+			want: 6000,
+		},
+		{
+			name:    "Deimos became untargetable for a phase",
+			species: deimos,
+			build: func(l *evtctest.Log) {
+				l.StateChange(1000, 23, gadget, boss, 1)
+				l.StateChange(4000, 8, boss, 5000, 0)
+				l.StateChange(6000, 24, gadget, 0, 0)
+			},
+			want: failedOutcome,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := newLog(tt.species, tt.build).Parse(t)
+
+			success, duration := Outcome(chain)
+			if tt.want == failedOutcome {
+				if success || duration != logDuration {
+					t.Errorf("Outcome = %v, %v; want false, %v", success, duration, logDuration)
+				}
+				return
+			}
+
+			want := time.Duration(tt.want-1000) * time.Millisecond
+			if !success || duration != want {
+				t.Errorf("Outcome = %v, %v; want true, %v", success, duration, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package encounter
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/BenLubar/evtc"
+)
+
+// Phase is a named part of an encounter.
+type Phase struct {
+	Name    string
+	Start   time.Time
+	End     time.Time
+	Targets []*evtc.Agent
+}
+
+// Duration returns the length of the phase.
+func (p Phase) Duration() time.Duration {
+	return p.End.Sub(p.Start)
+}
+
+// Contains reports whether t is within the phase.
+func (p Phase) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && !t.After(p.End)
+}
+
+// A PhaseFunc splits an encounter into phases. It is given the phase
+// covering the whole encounter and returns the phases within it.
+type PhaseFunc func(chain *evtc.EventChain, fight Phase) []Phase
+
+var (
+	phaseMu    sync.RWMutex
+	phaseFuncs = map[int]PhaseFunc{
+		15438: ByInvulnerability(757),   // Vale Guardian: Invulnerability
+		15429: ByInvulnerability(31877), // Gorseval: Protective Shadow
+		15375: ByInvulnerability(757),   // Sabetha: Invulnerability
+		16115: ByHealth(80, 60, 40),     // Matthias
+		17172: ByHealth(75, 50, 25),     // Mursaat Overseer
+		17188: ByInvulnerability(762),   // Samarog: Determined
+		17154: ByHealth(75, 50, 25, 10), // Deimos
+	}
+)
+
+// RegisterPhases sets the PhaseFunc used for encounters with the given boss
+// species, replacing any earlier registration. A nil PhaseFunc removes the
+// registration.
+func RegisterPhases(species int, f PhaseFunc) {
+	phaseMu.Lock()
+	defer phaseMu.Unlock()
+
+	if f == nil {
+		delete(phaseFuncs, species)
+	} else {
+		phaseFuncs[species] = f
+	}
+}
+
+// Phases returns the phases of the encounter in chain. The first phase,
+// named "Full Fight", always covers the whole encounter. It is followed by
+// the phases from the PhaseFunc registered for chain.BossSpecies, if any.
+func Phases(chain *evtc.EventChain) []Phase {
+	start, end := bounds(chain)
+	fight := Phase{
+		Name:    "Full Fight",
+		Start:   start,
+		End:     end,
+		Targets: Targets(chain),
+	}
+
+	phaseMu.RLock()
+	f := phaseFuncs[chain.BossSpecies]
+	phaseMu.RUnlock()
+
+	phases := []Phase{fight}
+	if f != nil {
+		phases = append(phases, f(chain, fight)...)
+	}
+	return phases
+}
+
+// ByHealth returns a PhaseFunc that starts a new phase each time the boss
+// first drops below one of the health thresholds. Thresholds are
+// percentages, in decreasing order.
+func ByHealth(thresholds ...float64) PhaseFunc {
+	return func(chain *evtc.EventChain, fight Phase) []Phase {
+		var breaks []time.Time
+		next := 0
+		for _, e := range chain.Events {
+			h, ok := e.(*evtc.HealthUpdateEvent)
+			if !ok || !isTarget(fight.Targets, h.Source) {
+				continue
+			}
+
+			for next < len(thresholds) && float64(h.Percentage)/100 < thresholds[next] {
+				breaks = append(breaks, h.LocalTime)
+				next++
+			}
+		}
+
+		var windows [][2]time.Time
+		start := fight.Start
+		for _, t := range breaks {
+			windows = append(windows, [2]time.Time{start, t})
+			start = t
+		}
+		windows = append(windows, [2]time.Time{start, fight.End})
+
+		return makePhases(fight, windows)
+	}
+}
+
+// ByInvulnerability returns a PhaseFunc that ends a phase whenever the boss
+// gains the given buff and starts the next phase when the buff is removed.
+func ByInvulnerability(buffID int) PhaseFunc {
+	return func(chain *evtc.EventChain, fight Phase) []Phase {
+		var toggles []toggle
+		stacks := 0
+		for _, e := range chain.Events {
+			switch e := e.(type) {
+			case *evtc.ApplyBuffEvent:
+				if e.SkillID != buffID || !isTarget(fight.Targets, e.Target) {
+					continue
+				}
+				if stacks == 0 {
+					toggles = append(toggles, toggle{e.LocalTime, false})
+				}
+				stacks++
+			case *evtc.BuffRemoveEvent:
+				if e.SkillID != buffID || !isTarget(fight.Targets, e.Target) || stacks == 0 {
+					continue
+				}
+				if stacks--; e.All {
+					stacks = 0
+				}
+				if stacks == 0 {
+					toggles = append(toggles, toggle{e.LocalTime, true})
+				}
+			}
+		}
+
+		return makePhases(fight, activeWindows(fight, toggles))
+	}
+}
+
+// ByTargetable returns a PhaseFunc that ends a phase whenever the boss
+// becomes untargetable and starts the next phase when it becomes targetable
+// again. The targetable state is read from the attack target gadget linked
+// to the boss.
+func ByTargetable() PhaseFunc {
+	return func(chain *evtc.EventChain, fight Phase) []Phase {
+		links := attackTargets(chain, fight.Targets)
+
+		var toggles []toggle
+		for _, e := range chain.Events {
+			if t, ok := e.(*evtc.TargetableEvent); ok && links[t.Source] != nil {
+				toggles = append(toggles, toggle{t.LocalTime, t.Targetable})
+			}
+		}
+
+		return makePhases(fight, activeWindows(fight, toggles))
+	}
+}
+
+type toggle struct {
+	at     time.Time
+	active bool
+}
+
+// activeWindows returns the parts of the fight during which the boss was
+// active, given the times it became active or inactive.
+func activeWindows(fight Phase, toggles []toggle) [][2]time.Time {
+	var windows [][2]time.Time
+	active, start := true, fight.Start
+	for _, t := range toggles {
+		if t.active == active {
+			continue
+		}
+		if active {
+			windows = append(windows, [2]time.Time{start, t.at})
+		}
+		active, start = t.active, t.at
+	}
+	if active {
+		windows = append(windows, [2]time.Time{start, fight.End})
+	}
+	return windows
+}
+
+// makePhases names each non-empty window "Phase 1", "Phase 2", and so on.
+func makePhases(fight Phase, windows [][2]time.Time) []Phase {
+	var phases []Phase
+	for _, w := range windows {
+		if !w[1].After(w[0]) {
+			continue
+		}
+		phases = append(phases, Phase{
+			Name:    "Phase " + strconv.Itoa(len(phases)+1),
+			Start:   w[0],
+			End:     w[1],
+			Targets: fight.Targets,
+		})
+	}
+	return phases
+}
@@ -0,0 +1,103 @@
+package encounter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+const (
+	player = 0x10
+	boss   = 0x20
+	gadget = 0x30
+
+	valeGuardian = 15438
+)
+
+// newLog returns a log of an encounter with the given boss species, with the
+// fight running from 1000ms to 8000ms.
+func newLog(species uint16, build func(l *evtctest.Log)) *evtctest.Log {
+	l := &evtctest.Log{Boss: species}
+	l.Player(player, evtc.Guardian, 0, "Player", ":Player.1234", 1)
+	l.NPC(boss, species, "Boss")
+	l.Gadget(gadget, 1, "Attack Target")
+
+	l.Damage(1000, player, boss, 9143, 100, 0)
+	build(l)
+	l.Damage(8000, player, boss, 9143, 100, 0)
+	return l
+}
+
+func TestPhases(t *testing.T) {
+	tests := []struct {
+		name  string
+		f     PhaseFunc
+		build func(l *evtctest.Log)
+		want  [][2]uint64
+	}{
+		{
+			name: "by health",
+			f:    ByHealth(75, 50),
+			build: func(l *evtctest.Log) {
+				l.StateChange(2000, 8, boss, 8000, 0)
+				l.StateChange(3000, 8, boss, 7000, 0)
+				// skipping past both thresholds at once
+				l.StateChange(4000, 8, boss, 4000, 0)
+			},
+			want: [][2]uint64{{1000, 3000}, {3000, 4000}, {4000, 8000}},
+		},
+		{
+			name: "by invulnerability",
+			f:    ByInvulnerability(757),
+			build: func(l *evtctest.Log) {
+				l.ApplyBuff(3000, boss, boss, 757, 0, 1, true)
+				l.ApplyBuff(3500, boss, boss, 757, 0, 2, true)
+				l.RemoveBuff(4000, 0, boss, 757, 2, 1)
+				l.RemoveBuff(5000, 0, boss, 757, 1, 0)
+			},
+			want: [][2]uint64{{1000, 3000}, {5000, 8000}},
+		},
+		{
+			name: "by targetable",
+			f:    ByTargetable(),
+			build: func(l *evtctest.Log) {
+				l.StateChange(1000, 23, gadget, boss, 1) // CBTS_ATTACKTARGET
+				l.StateChange(3000, 24, gadget, 0, 0)    // CBTS_TARGETABLE
+				l.StateChange(5000, 24, gadget, 1, 0)
+			},
+			want: [][2]uint64{{1000, 3000}, {5000, 8000}},
+		},
+		{
+			name: "by targetable ignores unlinked gadgets",
+			f:    ByTargetable(),
+			build: func(l *evtctest.Log) {
+				l.StateChange(3000, 24, gadget, 0, 0)
+			},
+			want: [][2]uint64{{1000, 8000}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := newLog(valeGuardian, tt.build).Parse(t)
+
+			fight := Phases(chain)[0]
+			if fight.Start != evtctest.At(1000) || fight.End != evtctest.At(8000) {
+				t.Fatalf("full fight is %v to %v", fight.Start, fight.End)
+			}
+
+			var got [][2]uint64
+			for _, p := range tt.f(chain, fight) {
+				got = append(got, [2]uint64{
+					uint64(p.Start.Sub(evtctest.At(0)).Milliseconds()),
+					uint64(p.End.Sub(evtctest.At(0)).Milliseconds()),
+				})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("phases = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package encounter
+
+import (
+	"sync"
+
+	"github.com/BenLubar/evtc"
+)
+
+// Category is the kind of content an encounter belongs to.
+type Category int
+
+const (
+	UnknownCategory Category = iota
+	Raid
+	Strike
+	Fractal
+)
+
+func (c Category) String() string {
+	switch c {
+	case Raid:
+		return "Raid"
+	case Strike:
+		return "Strike Mission"
+	case Fractal:
+		return "Fractal"
+	default:
+		return "Unknown"
+	}
+}
+
+// Encounter describes a boss encounter.
+type Encounter struct {
+	Name     string
+	Category Category
+
+	// Group is the raid wing, strike mission release, or fractal the
+	// encounter is part of.
+	Group string
+
+	// Targets are the species IDs of every agent that must be defeated.
+	// Bosses that are a different species in challenge mode are listed
+	// under both IDs.
+	Targets []int
+
+	// ChallengeModeHealth is the maximum health above which the
+	// encounter is in challenge mode. It is 0 for encounters whose
+	// challenge mode cannot be detected from health alone.
+	ChallengeModeHealth uint64
+}
+
+// encounters lists the raids, strike missions, and challenge mode fractals.
+// The Forging Steel and Dragonstorm strike missions are left out because
+// they have no single boss to identify them by.
+var encounters = []*Encounter{
+	{"Vale Guardian", Raid, "Spirit Vale", []int{15438}, 0},
+	{"Gorseval the Multifarious", Raid, "Spirit Vale", []int{15429}, 0},
+	{"Sabetha the Saboteur", Raid, "Spirit Vale", []int{15375}, 0},
+
+	{"Slothasor", Raid, "Salvation Pass", []int{16123}, 0},
+	{"Bandit Trio", Raid, "Salvation Pass", []int{16088, 16137, 16125}, 0},
+	{"Matthias Gabrel", Raid, "Salvation Pass", []int{16115}, 0},
+
+	{"Escort", Raid, "Stronghold of the Faithful", []int{16253}, 0},
+	{"Keep Construct", Raid, "Stronghold of the Faithful", []int{16235}, 0},
+	{"Xera", Raid, "Stronghold of the Faithful", []int{16246}, 0},
+
+	{"Cairn the Indomitable", Raid, "Bastion of the Penitent", []int{17194}, 0},
+	{"Mursaat Overseer", Raid, "Bastion of the Penitent", []int{17172}, 25000000},
+	{"Samarog", Raid, "Bastion of the Penitent", []int{17188}, 30000000},
+	{"Deimos", Raid, "Bastion of the Penitent", []int{17154}, 40000000},
+
+	{"Soulless Horror", Raid, "Hall of Chains", []int{19767}, 0},
+	{"River of Souls", Raid, "Hall of Chains", []int{19828}, 0},
+	{"Broken King", Raid, "Hall of Chains", []int{19691}, 0},
+	{"Eater of Souls", Raid, "Hall of Chains", []int{19536}, 0},
+	{"Statue of Darkness", Raid, "Hall of Chains", []int{19651, 19844}, 0},
+	{"Dhuum", Raid, "Hall of Chains", []int{19450}, 35000000},
+
+	{"Conjured Amalgamate", Raid, "Mythwright Gambit", []int{43974}, 0},
+	{"Twin Largos", Raid, "Mythwright Gambit", []int{21105, 21089}, 18000000},
+	{"Qadim", Raid, "Mythwright Gambit", []int{20934}, 21000000},
+
+	{"Cardinal Adina", Raid, "The Key of Ahdashim", []int{22006}, 23000000},
+	{"Cardinal Sabir", Raid, "The Key of Ahdashim", []int{21964}, 32000000},
+	{"Qadim the Peerless", Raid, "The Key of Ahdashim", []int{22000}, 48000000},
+
+	{"Greer, the Blightbringer", Raid, "Mount Balrior", []int{26725}, 0},
+	{"Decima, the Stormsinger", Raid, "Mount Balrior", []int{26774, 26867}, 0},
+	{"Ura, the Steamshrieker", Raid, "Mount Balrior", []int{26712}, 0},
+
+	{"Icebrood Construct", Strike, "Icebrood Saga", []int{22154}, 0},
+	{"Voice and Claw of the Fallen", Strike, "Icebrood Saga", []int{22343, 22481}, 0},
+	{"Fraenir of Jormag", Strike, "Icebrood Saga", []int{22492}, 0},
+	{"Boneskinner", Strike, "Icebrood Saga", []int{22521}, 0},
+	{"Whisper of Jormag", Strike, "Icebrood Saga", []int{22711}, 0},
+	{"Cold War", Strike, "Icebrood Saga", []int{22836}, 0},
+
+	{"Aetherblade Hideout", Strike, "End of Dragons", []int{24033}, 0},
+	{"Xunlai Jade Junkyard", Strike, "End of Dragons", []int{23957}, 0},
+	{"Kaineng Overlook", Strike, "End of Dragons", []int{24485, 24266}, 0},
+	{"Harvest Temple", Strike, "End of Dragons", []int{43488}, 0}, // the Dragonvoid is a gadget
+	{"Old Lion's Court", Strike, "End of Dragons", []int{25413, 25415, 25419}, 0},
+
+	{"Cosmic Observatory", Strike, "Secrets of the Obscure", []int{25705}, 0},
+	{"Temple of Febe", Strike, "Secrets of the Obscure", []int{25989}, 0},
+
+	{"MAMA", Fractal, "Nightmare", []int{17021}, 0},
+	{"Siax the Corrupted", Fractal, "Nightmare", []int{17028}, 0},
+	{"Ensolyss of the Endless Torment", Fractal, "Nightmare", []int{16948}, 0},
+	{"Skorvald the Shattered", Fractal, "Shattered Observatory", []int{17632}, 5551000},
+	{"Artsariiv", Fractal, "Shattered Observatory", []int{17949}, 0},
+	{"Arkk", Fractal, "Shattered Observatory", []int{17759}, 0},
+	{"Ai, Keeper of the Peak", Fractal, "Sunqua Peak", []int{23254}, 0},
+	{"Kanaxai, Scythe of House Aurkus", Fractal, "Silent Surf", []int{25572, 25577}, 0},
+	{"Eparch", Fractal, "Lonely Tower", []int{26231}, 0},
+}
+
+var (
+	speciesMu sync.RWMutex
+
+	// species maps the species ID of every target to its encounter.
+	species = func() map[int]*Encounter {
+		m := make(map[int]*Encounter)
+		for _, e := range encounters {
+			for _, id := range e.Targets {
+				m[id] = e
+			}
+		}
+		return m
+	}()
+)
+
+// Register adds an encounter to the registry, replacing the registration of
+// any of its targets.
+func Register(e Encounter) {
+	speciesMu.Lock()
+	defer speciesMu.Unlock()
+
+	e.Targets = append([]int(nil), e.Targets...)
+	for _, id := range e.Targets {
+		species[id] = &e
+	}
+}
+
+// Lookup returns the encounter that the given species takes part in.
+func Lookup(speciesID int) (Encounter, bool) {
+	speciesMu.RLock()
+	defer speciesMu.RUnlock()
+
+	if e, ok := species[speciesID]; ok {
+		return *e, true
+	}
+	return Encounter{}, false
+}
+
+// Identify returns the encounter recorded in chain, based on
+// chain.BossSpecies. It returns false for encounters that are not in the
+// registry.
+func Identify(chain *evtc.EventChain) (Encounter, bool) {
+	return Lookup(chain.BossSpecies)
+}
+
+// IsChallengeMode reports whether the encounter in chain is in challenge
+// mode, based on the highest maximum health of its targets. It returns false
+// for encounters whose challenge mode cannot be detected from health and for
+// encounters that are not in the registry; use Identify to tell these apart.
+func IsChallengeMode(chain *evtc.EventChain) bool {
+	e, ok := Identify(chain)
+	if !ok || e.ChallengeModeHealth == 0 {
+		return false
+	}
+
+	targets := Targets(chain)
+	var maxHealth uint64
+	for _, ev := range chain.Events {
+		if m, ok := ev.(*evtc.MaxHealthUpdateEvent); ok && isTarget(targets, m.Source) && m.MaxHealth > maxHealth {
+			maxHealth = m.MaxHealth
+		}
+	}
+
+	return maxHealth > e.ChallengeModeHealth
+}
@@ -0,0 +1,69 @@
+package encounter
+
+import (
+	"testing"
+
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		species  int
+		name     string
+		category Category
+		ok       bool
+	}{
+		{15438, "Vale Guardian", Raid, true},
+		{16137, "Bandit Trio", Raid, true},
+		{16253, "Escort", Raid, true},
+		{26774, "Decima, the Stormsinger", Raid, true},
+		{26867, "Decima, the Stormsinger", Raid, true},
+		{22343, "Voice and Claw of the Fallen", Strike, true},
+		{24266, "Kaineng Overlook", Strike, true},
+		{25989, "Temple of Febe", Strike, true},
+		{23254, "Ai, Keeper of the Peak", Fractal, true},
+		{1, "", UnknownCategory, false},
+	}
+
+	for _, tt := range tests {
+		e, ok := Lookup(tt.species)
+		if ok != tt.ok || e.Name != tt.name || e.Category != tt.category {
+			t.Errorf("Lookup(%d) = %q, %v, %v; want %q, %v, %v", tt.species, e.Name, e.Category, ok, tt.name, tt.category, tt.ok)
+		}
+	}
+}
+
+func TestRegistryIsUnambiguous(t *testing.T) {
+	seen := make(map[int]string)
+	for _, e := range encounters {
+		for _, id := range e.Targets {
+			if other, ok := seen[id]; ok {
+				t.Errorf("species %d is a target of both %q and %q", id, other, e.Name)
+			}
+			seen[id] = e.Name
+		}
+	}
+}
+
+func TestIsChallengeMode(t *testing.T) {
+	tests := []struct {
+		species   uint16
+		maxHealth int64
+		want      bool
+	}{
+		{deimos, 50000000, true},
+		{deimos, 35000000, false},
+		{valeGuardian, 50000000, false}, // not detectable from health
+		{unregistered, 50000000, false},
+	}
+
+	for _, tt := range tests {
+		chain := newLog(tt.species, func(l *evtctest.Log) {
+			l.Add(evtctest.Record{Time: 1000, IsStateChange: 12, SrcAgent: boss, DstAgent: uint64(tt.maxHealth)}) // CBTS_MAXHEALTHUPDATE
+		}).Parse(t)
+
+		if got := IsChallengeMode(chain); got != tt.want {
+			t.Errorf("IsChallengeMode(species %d, max health %d) = %v; want %v", tt.species, tt.maxHealth, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package health tracks the health and barrier of agents over the course of
+// a log.
+package health
+
+import (
+	"time"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/series"
+)
+
+// Sample is the health and barrier of an agent from Time until the next
+// sample, both as percentages of maximum health.
+type Sample struct {
+	Time    time.Time
+	Health  float64
+	Barrier float64
+}
+
+// Effective returns the health plus the barrier, as a percentage of maximum
+// health. It can be over 100.
+func (s Sample) Effective() float64 {
+	return s.Health + s.Barrier
+}
+
+// Timeline is the health and barrier history of one agent.
+type Timeline struct {
+	Agent *evtc.Agent
+
+	// Samples holds one entry for each time the health or barrier of the
+	// agent changed, in chronological order.
+	Samples []Sample
+}
+
+// At returns the health and barrier of the agent at t. The agent is assumed
+// to be at full health with no barrier before the first sample.
+func (tl *Timeline) At(t time.Time) Sample {
+	current := Sample{Health: 100}
+	for _, s := range tl.Samples {
+		if s.Time.After(t) {
+			break
+		}
+		current = s
+	}
+	current.Time = t
+	return current
+}
+
+func (tl *Timeline) last() Sample {
+	if n := len(tl.Samples); n != 0 {
+		return tl.Samples[n-1]
+	}
+	return Sample{Health: 100}
+}
+
+func (tl *Timeline) record(s Sample) {
+	tl.Samples = series.Append(tl.Samples, s, func(s Sample) time.Time { return s.Time })
+}
+
+// Tracker builds health timelines from events. Events must be added in the
+// order they appear in the log.
+type Tracker struct {
+	timelines series.Index[Timeline]
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Track builds the health timeline of every agent in chain.
+func Track(chain *evtc.EventChain) []*Timeline {
+	t := NewTracker()
+	for _, e := range chain.Events {
+		t.Add(e)
+	}
+	return t.Timelines()
+}
+
+// Timeline returns the health timeline of an agent, or nil if the log has no
+// health or barrier updates for the agent.
+func (t *Tracker) Timeline(a *evtc.Agent) *Timeline {
+	return t.timelines.Get(a)
+}
+
+// Timelines returns every timeline, in the order each agent's health or
+// barrier first changed.
+func (t *Tracker) Timelines() []*Timeline {
+	return t.timelines.All()
+}
+
+func (t *Tracker) timeline(a *evtc.Agent) *Timeline {
+	return t.timelines.GetOrAdd(a, func() *Timeline {
+		return &Timeline{
+			Agent: a,
+		}
+	})
+}
+
+// Add updates the timelines with a single event. Events other than health
+// and barrier updates are ignored.
+func (t *Tracker) Add(e evtc.Event) {
+	switch e := e.(type) {
+	case *evtc.HealthUpdateEvent:
+		if e.Source == nil {
+			return
+		}
+
+		tl := t.timeline(e.Source)
+		s := tl.last()
+		s.Time = e.LocalTime
+		s.Health = float64(e.Percentage) / 100
+		tl.record(s)
+	case *evtc.BarrierUpdateEvent:
+		if e.Source == nil {
+			return
+		}
+
+		tl := t.timeline(e.Source)
+		s := tl.last()
+		s.Time = e.LocalTime
+		s.Barrier = float64(e.Percentage) / 100
+		tl.record(s)
+	}
+}
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+const (
+	player = 0x10
+	boss   = 0x20
+)
+
+func healthUpdate(l *evtctest.Log, ms uint64, src uint64, percent float64) {
+	l.StateChange(ms, 8, src, uint64(percent*100), 0) // CBTS_HEALTHUPDATE
+}
+
+func barrierUpdate(l *evtctest.Log, ms uint64, src uint64, percent float64) {
+	l.StateChange(ms, 38, src, uint64(percent*100), 0) // CBTS_BARRIERUPDATE
+}
+
+func TestTracker(t *testing.T) {
+	type sample struct {
+		health, barrier float64
+	}
+
+	tests := []struct {
+		name  string
+		build func(l *evtctest.Log)
+
+		at      map[uint64]sample
+		samples int
+	}{
+		{
+			name: "health and barrier",
+			build: func(l *evtctest.Log) {
+				healthUpdate(l, 2000, player, 80)
+				barrierUpdate(l, 3000, player, 25.5)
+				healthUpdate(l, 4000, player, 50)
+				barrierUpdate(l, 5000, player, 0)
+			},
+			at: map[uint64]sample{
+				1000: {100, 0},
+				2000: {80, 0},
+				3500: {80, 25.5},
+				4000: {50, 25.5},
+				9000: {50, 0},
+			},
+			samples: 4,
+		},
+		{
+			name: "updates at the same time",
+			build: func(l *evtctest.Log) {
+				healthUpdate(l, 2000, player, 90)
+				barrierUpdate(l, 2000, player, 10)
+				healthUpdate(l, 2000, player, 70)
+			},
+			at: map[uint64]sample{
+				1999: {100, 0},
+				2000: {70, 10},
+			},
+			samples: 1,
+		},
+		{
+			name: "agents are tracked separately",
+			build: func(l *evtctest.Log) {
+				healthUpdate(l, 2000, boss, 10)
+				healthUpdate(l, 3000, player, 60)
+			},
+			at: map[uint64]sample{
+				2500: {100, 0},
+				3000: {60, 0},
+			},
+			samples: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &evtctest.Log{}
+			l.Player(player, evtc.Guardian, 0, "Player", ":Player.1234", 1)
+			l.NPC(boss, 15438, "Boss")
+			tt.build(l)
+			chain := l.Parse(t)
+
+			var tl *Timeline
+			for _, timeline := range Track(chain) {
+				if timeline.Agent.Address() == player {
+					tl = timeline
+				}
+			}
+			if tl == nil {
+				t.Fatal("no timeline for the player")
+			}
+
+			if len(tl.Samples) != tt.samples {
+				t.Errorf("%d samples; want %d", len(tl.Samples), tt.samples)
+			}
+			for at, want := range tt.at {
+				s := tl.At(evtctest.At(at))
+				if got := (sample{s.Health, s.Barrier}); got != want {
+					t.Errorf("At(%d) = %v; want %v", at, got, want)
+				}
+				if s.Time != evtctest.At(at) {
+					t.Errorf("At(%d).Time = %v", at, s.Time)
+				}
+				if s.Effective() != want.health+want.barrier {
+					t.Errorf("At(%d).Effective() = %v; want %v", at, s.Effective(), want.health+want.barrier)
+				}
+			}
+		})
+	}
+}
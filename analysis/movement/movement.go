@@ -0,0 +1,275 @@
+// Package movement assembles the position, velocity, and facing updates in a
+// log into per-agent tracks that can be sampled at any time.
+package movement
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/BenLubar/evtc"
+)
+
+// Vector is a position, velocity, or direction in game units.
+type Vector struct {
+	X, Y, Z float64
+}
+
+// Sub returns v - o.
+func (v Vector) Sub(o Vector) Vector {
+	return Vector{v.X - o.X, v.Y - o.Y, v.Z - o.Z}
+}
+
+// Len returns the length of v.
+func (v Vector) Len() float64 {
+	return math.Sqrt(v.X*v.X + v.Y*v.Y + v.Z*v.Z)
+}
+
+// Dist returns the distance between v and o.
+func (v Vector) Dist(o Vector) float64 {
+	return v.Sub(o).Len()
+}
+
+// Sample is a vector recorded at a point in time.
+type Sample struct {
+	Time   time.Time
+	Vector Vector
+}
+
+// Track is the movement history of one agent. Each slice is in
+// chronological order.
+type Track struct {
+	Agent      *evtc.Agent
+	Positions  []Sample
+	Velocities []Sample
+	Facings    []Sample
+}
+
+// search returns the index of the last sample at or before t, or -1.
+func search(samples []Sample, t time.Time) int {
+	return sort.Search(len(samples), func(i int) bool { return samples[i].Time.After(t) }) - 1
+}
+
+// PositionAt returns the position of the agent at t, interpolating linearly
+// between position updates. arcdps only records a position when it changes,
+// so the last known position is used after the final update. It returns
+// false before the first update.
+func (tr *Track) PositionAt(t time.Time) (Vector, bool) {
+	i := search(tr.Positions, t)
+	if i < 0 {
+		return Vector{}, false
+	}
+	if i == len(tr.Positions)-1 {
+		return tr.Positions[i].Vector, true
+	}
+
+	a, b := tr.Positions[i], tr.Positions[i+1]
+	span := b.Time.Sub(a.Time)
+	if span <= 0 {
+		return b.Vector, true
+	}
+
+	f := float64(t.Sub(a.Time)) / float64(span)
+	return Vector{
+		X: a.Vector.X + (b.Vector.X-a.Vector.X)*f,
+		Y: a.Vector.Y + (b.Vector.Y-a.Vector.Y)*f,
+		Z: a.Vector.Z + (b.Vector.Z-a.Vector.Z)*f,
+	}, true
+}
+
+// VelocityAt returns the most recent velocity of the agent at t.
+func (tr *Track) VelocityAt(t time.Time) (Vector, bool) {
+	if i := search(tr.Velocities, t); i >= 0 {
+		return tr.Velocities[i].Vector, true
+	}
+	return Vector{}, false
+}
+
+// FacingAt returns the most recent facing direction of the agent at t. The
+// Z component is always 0.
+func (tr *Track) FacingAt(t time.Time) (Vector, bool) {
+	if i := search(tr.Facings, t); i >= 0 {
+		return tr.Facings[i].Vector, true
+	}
+	return Vector{}, false
+}
+
+// Tracks holds the movement track of every agent in a log.
+type Tracks struct {
+	tracks map[*evtc.Agent]*Track
+	order  []*Track
+}
+
+// Build assembles the movement tracks of every agent in chain.
+func Build(chain *evtc.EventChain) *Tracks {
+	ts := &Tracks{
+		tracks: make(map[*evtc.Agent]*Track),
+	}
+
+	for _, e := range chain.Events {
+		switch e := e.(type) {
+		case *evtc.PositionEvent:
+			if tr := ts.track(e.Source); tr != nil {
+				tr.Positions = append(tr.Positions, Sample{e.LocalTime, Vector{float64(e.X), float64(e.Y), float64(e.Z)}})
+			}
+		case *evtc.VelocityEvent:
+			if tr := ts.track(e.Source); tr != nil {
+				tr.Velocities = append(tr.Velocities, Sample{e.LocalTime, Vector{float64(e.X), float64(e.Y), float64(e.Z)}})
+			}
+		case *evtc.FacingEvent:
+			if tr := ts.track(e.Source); tr != nil {
+				tr.Facings = append(tr.Facings, Sample{e.LocalTime, Vector{float64(e.X), float64(e.Y), 0}})
+			}
+		}
+	}
+
+	// arcdps does not always log events in order
+	for _, tr := range ts.order {
+		for _, samples := range [][]Sample{tr.Positions, tr.Velocities, tr.Facings} {
+			sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+		}
+	}
+
+	return ts
+}
+
+func (ts *Tracks) track(a *evtc.Agent) *Track {
+	if a == nil {
+		return nil
+	}
+	if tr, ok := ts.tracks[a]; ok {
+		return tr
+	}
+
+	tr := &Track{Agent: a}
+	ts.tracks[a] = tr
+	ts.order = append(ts.order, tr)
+	return tr
+}
+
+// Track returns the movement track of an agent, or nil if the agent never
+// moved.
+func (ts *Tracks) Track(a *evtc.Agent) *Track {
+	return ts.tracks[a]
+}
+
+// All returns every track, in the order each agent first moved.
+func (ts *Tracks) All() []*Track {
+	return append([]*Track(nil), ts.order...)
+}
+
+// PositionAt returns the position of an agent at t. See Track.PositionAt.
+func (ts *Tracks) PositionAt(a *evtc.Agent, t time.Time) (Vector, bool) {
+	tr := ts.tracks[a]
+	if tr == nil {
+		return Vector{}, false
+	}
+	return tr.PositionAt(t)
+}
+
+// Distance returns the distance between two agents at t. It returns false if
+// the position of either agent is unknown.
+func (ts *Tracks) Distance(a, b *evtc.Agent, t time.Time) (float64, bool) {
+	pa, ok := ts.PositionAt(a, t)
+	if !ok {
+		return 0, false
+	}
+	pb, ok := ts.PositionAt(b, t)
+	if !ok {
+		return 0, false
+	}
+	return pa.Dist(pb), true
+}
+
+// StackDistance returns the average distance of the agents from their
+// center at t. Agents whose position is unknown are left out. It returns
+// false if no agent's position is known.
+func (ts *Tracks) StackDistance(agents []*evtc.Agent, t time.Time) (float64, bool) {
+	var positions []Vector
+	var center Vector
+	for _, a := range agents {
+		if p, ok := ts.PositionAt(a, t); ok {
+			positions = append(positions, p)
+			center.X += p.X
+			center.Y += p.Y
+			center.Z += p.Z
+		}
+	}
+	if len(positions) == 0 {
+		return 0, false
+	}
+
+	n := float64(len(positions))
+	center = Vector{center.X / n, center.Y / n, center.Z / n}
+
+	var total float64
+	for _, p := range positions {
+		total += p.Dist(center)
+	}
+	return total / n, true
+}
+
+// Stats summarizes a distance sampled at regular intervals.
+type Stats struct {
+	Samples int
+	Mean    float64
+	Min     float64
+	Max     float64
+}
+
+func (s *Stats) add(d float64) {
+	if s.Samples == 0 || d < s.Min {
+		s.Min = d
+	}
+	if s.Samples == 0 || d > s.Max {
+		s.Max = d
+	}
+	s.Mean += (d - s.Mean) / float64(s.Samples+1)
+	s.Samples++
+}
+
+func sample(start, end time.Time, step time.Duration, f func(time.Time) (float64, bool)) Stats {
+	if step <= 0 {
+		step = time.Second
+	}
+
+	var s Stats
+	for t := start; !t.After(end); t = t.Add(step) {
+		if d, ok := f(t); ok {
+			s.add(d)
+		}
+	}
+	return s
+}
+
+// DistanceStats samples the distance between a and ref every step from start
+// to end. Pass the commander as ref to measure how closely a player followed
+// the tag. A step of 0 samples once per second.
+func (ts *Tracks) DistanceStats(a, ref *evtc.Agent, start, end time.Time, step time.Duration) Stats {
+	return sample(start, end, step, func(t time.Time) (float64, bool) {
+		return ts.Distance(a, ref, t)
+	})
+}
+
+// StackDistanceStats samples the stack distance of the agents every step
+// from start to end. A step of 0 samples once per second.
+func (ts *Tracks) StackDistanceStats(agents []*evtc.Agent, start, end time.Time, step time.Duration) Stats {
+	return sample(start, end, step, func(t time.Time) (float64, bool) {
+		return ts.StackDistance(agents, t)
+	})
+}
+
+// TagDistanceStats samples the distance between a and whichever player had a
+// commander tag every step from start to end, using tags from
+// EventChain.Commanders. Times when no other player had a tag are left out.
+// A step of 0 samples once per second.
+func (ts *Tracks) TagDistanceStats(a *evtc.Agent, tags []evtc.CommanderTag, start, end time.Time, step time.Duration) Stats {
+	return sample(start, end, step, func(t time.Time) (float64, bool) {
+		for _, tag := range tags {
+			if tag.Agent != a && tag.Contains(t) {
+				return ts.Distance(a, tag.Agent, t)
+			}
+		}
+		return 0, false
+	})
+}
@@ -0,0 +1,136 @@
+package movement
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+const (
+	player    = 0x10
+	commander = 0x11
+	idle      = 0x12
+)
+
+func build(t *testing.T, positions func(l *evtctest.Log)) (*Tracks, map[uint64]*evtc.Agent) {
+	t.Helper()
+
+	l := &evtctest.Log{}
+	l.Player(player, evtc.Warrior, 0, "Player", ":Player.1234", 1)
+	l.Player(commander, evtc.Guardian, 0, "Commander", ":Commander.1234", 1)
+	l.Player(idle, evtc.Mesmer, 0, "Idle", ":Idle.1234", 1)
+	positions(l)
+	chain := l.Parse(t)
+
+	agents := make(map[uint64]*evtc.Agent)
+	for _, a := range chain.Agents() {
+		agents[a.Address()] = a
+	}
+	return Build(chain), agents
+}
+
+func TestPositionAt(t *testing.T) {
+	ts, agents := build(t, func(l *evtctest.Log) {
+		l.Position(1000, player, 0, 0, 0)
+		// out of order
+		l.Position(3000, player, 100, 200, 0)
+		l.Position(2000, player, 100, 0, 0)
+		l.Position(3000, player, 100, 200, 50)
+	})
+
+	tests := []struct {
+		at   uint64
+		want Vector
+		ok   bool
+	}{
+		{at: 500},
+		{at: 1000, want: Vector{0, 0, 0}, ok: true},
+		{at: 1500, want: Vector{50, 0, 0}, ok: true},
+		{at: 2000, want: Vector{100, 0, 0}, ok: true},
+		{at: 2250, want: Vector{100, 50, 0}, ok: true},
+		// two updates at the same time
+		{at: 3000, want: Vector{100, 200, 50}, ok: true},
+		{at: 9000, want: Vector{100, 200, 50}, ok: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := ts.PositionAt(agents[player], evtctest.At(tt.at))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("PositionAt(%d) = %v, %v; want %v, %v", tt.at, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, ok := ts.PositionAt(agents[idle], evtctest.At(2000)); ok {
+		t.Error("PositionAt returned a position for an agent that never moved")
+	}
+	if ts.Track(agents[idle]) != nil {
+		t.Error("Track returned a track for an agent that never moved")
+	}
+}
+
+func TestDistanceStats(t *testing.T) {
+	ts, agents := build(t, func(l *evtctest.Log) {
+		l.Position(1000, player, 0, 0, 0)
+		l.Position(1000, commander, 30, 40, 0)
+		l.Position(3000, player, 30, 0, 0)
+	})
+
+	tag := func(addr uint64, start, end uint64) evtc.CommanderTag {
+		return evtc.CommanderTag{Agent: agents[addr], MarkerID: 1, Start: evtctest.At(start), End: evtctest.At(end)}
+	}
+
+	tests := []struct {
+		name  string
+		stats func() Stats
+		want  Stats
+	}{
+		{
+			name: "distance",
+			stats: func() Stats {
+				return ts.DistanceStats(agents[player], agents[commander], evtctest.At(0), evtctest.At(3000), 0)
+			},
+			// 0ms is before the first update
+			want: Stats{Samples: 3, Mean: (50 + math.Sqrt(15*15+40*40) + 40) / 3, Min: 40, Max: 50},
+		},
+		{
+			name: "unknown reference",
+			stats: func() Stats {
+				return ts.DistanceStats(agents[player], agents[idle], evtctest.At(0), evtctest.At(3000), 0)
+			},
+		},
+		{
+			name: "stack distance",
+			stats: func() Stats {
+				return ts.StackDistanceStats([]*evtc.Agent{agents[player], agents[commander], agents[idle]}, evtctest.At(1000), evtctest.At(3000), 2*time.Second)
+			},
+			want: Stats{Samples: 2, Mean: 22.5, Min: 20, Max: 25},
+		},
+		{
+			name: "tag distance",
+			stats: func() Stats {
+				tags := []evtc.CommanderTag{
+					tag(player, 0, 9000),
+					tag(commander, 2500, 9000),
+				}
+				return ts.TagDistanceStats(agents[player], tags, evtctest.At(1000), evtctest.At(3000), 500*time.Millisecond)
+			},
+			// the player's own tag is skipped
+			want: Stats{Samples: 2, Mean: (math.Sqrt(7.5*7.5+40*40) + 40) / 2, Min: 40, Max: math.Sqrt(7.5*7.5 + 40*40)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.stats()
+			if got.Samples != tt.want.Samples ||
+				math.Abs(got.Mean-tt.want.Mean) > 1e-9 ||
+				got.Min != tt.want.Min ||
+				math.Abs(got.Max-tt.want.Max) > 1e-9 {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package rotation pairs skill activations with their completions to
+// reconstruct the order in which each agent used their skills.
+package rotation
+
+import (
+	"time"
+
+	"github.com/BenLubar/evtc"
+)
+
+// Cast is one use of a skill.
+type Cast struct {
+	SkillID   int
+	SkillName string
+
+	// Start is when the activation began. It is the zero time if the
+	// log does not include the start of the activation.
+	Start time.Time
+	// End is when the activation finished or was cancelled. It is the
+	// zero time if no completion was recorded, either because the log
+	// ended first or because the agent began another activation.
+	End time.Time
+
+	// ExpectedDuration is how long arcdps expected the activation to
+	// take when it began.
+	ExpectedDuration time.Duration
+	// Duration is how long the activation actually took.
+	Duration time.Duration
+
+	// Quickness is true if the agent had quickness when the activation
+	// began.
+	Quickness bool
+	// Cancelled is true if the activation stopped before reaching the
+	// point where the skill takes effect.
+	Cancelled bool
+	// Reset is true if the animation played out in full.
+	Reset bool
+}
+
+// TimeLost returns the time spent in the animation of a cancelled cast, and
+// 0 for any other cast.
+func (c *Cast) TimeLost() time.Duration {
+	if c.Cancelled {
+		return c.Duration
+	}
+	return 0
+}
+
+// WeaponSwap is a change of weapon set.
+type WeaponSwap struct {
+	Time time.Time
+	// WeaponSet is the new weapon set: 0 and 1 underwater, 4 and 5 on
+	// land.
+	WeaponSet int
+}
+
+// Entry is one step of a rotation. Exactly one of Cast and Swap is set.
+type Entry struct {
+	Cast *Cast
+	Swap *WeaponSwap
+}
+
+// Rotation is the skill usage of one agent, in the order each cast began.
+type Rotation struct {
+	Agent   *evtc.Agent
+	Entries []Entry
+
+	// pending is the cast in progress. An agent only casts one skill at
+	// a time, so it is abandoned when the next activation begins.
+	pending *Cast
+}
+
+// Casts returns every cast in the rotation.
+func (r *Rotation) Casts() []*Cast {
+	var casts []*Cast
+	for _, e := range r.Entries {
+		if e.Cast != nil {
+			casts = append(casts, e.Cast)
+		}
+	}
+	return casts
+}
+
+// Cancelled returns every cancelled cast in the rotation.
+func (r *Rotation) Cancelled() []*Cast {
+	var casts []*Cast
+	for _, e := range r.Entries {
+		if e.Cast != nil && e.Cast.Cancelled {
+			casts = append(casts, e.Cast)
+		}
+	}
+	return casts
+}
+
+// TimeLost returns the total animation time lost to cancelled casts.
+func (r *Rotation) TimeLost() time.Duration {
+	var lost time.Duration
+	for _, e := range r.Entries {
+		if e.Cast != nil {
+			lost += e.Cast.TimeLost()
+		}
+	}
+	return lost
+}
+
+// Builder builds rotations from events. Events must be added in the order
+// they appear in the log.
+type Builder struct {
+	rotations map[*evtc.Agent]*Rotation
+	order     []*Rotation
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{
+		rotations: make(map[*evtc.Agent]*Rotation),
+	}
+}
+
+// Build builds the rotation of every agent in chain.
+func Build(chain *evtc.EventChain) []*Rotation {
+	b := NewBuilder()
+	for _, e := range chain.Events {
+		b.Add(e)
+	}
+	return b.Rotations()
+}
+
+// Rotation returns the rotation of an agent, or nil if the agent never used
+// a skill.
+func (b *Builder) Rotation(a *evtc.Agent) *Rotation {
+	return b.rotations[a]
+}
+
+// Rotations returns every rotation, in the order each agent first used a
+// skill.
+func (b *Builder) Rotations() []*Rotation {
+	return append([]*Rotation(nil), b.order...)
+}
+
+func (b *Builder) rotation(a *evtc.Agent) *Rotation {
+	if r, ok := b.rotations[a]; ok {
+		return r
+	}
+
+	r := &Rotation{
+		Agent: a,
+	}
+	b.rotations[a] = r
+	b.order = append(b.order, r)
+	return r
+}
+
+// Add updates the rotations with a single event. Events other than skill
+// activations and weapon swaps are ignored.
+func (b *Builder) Add(e evtc.Event) {
+	switch e := e.(type) {
+	case *evtc.SkillActivationEvent:
+		if e.Source == nil {
+			return
+		}
+
+		r := b.rotation(e.Source)
+		c := &Cast{
+			SkillID:          e.SkillID,
+			SkillName:        e.SkillName,
+			Start:            e.LocalTime,
+			ExpectedDuration: e.ExpectedDuration,
+			Quickness:        e.Quickness,
+		}
+		r.Entries = append(r.Entries, Entry{Cast: c})
+		r.pending = c
+	case *evtc.SkillActivatedEvent:
+		if e.Source == nil {
+			return
+		}
+
+		r := b.rotation(e.Source)
+		c := r.pending
+		if c != nil && c.SkillID == e.SkillID {
+			r.pending = nil
+		} else {
+			// the start of the activation was not recorded, for
+			// example because it began before the log started
+			c = &Cast{
+				SkillID:   e.SkillID,
+				SkillName: e.SkillName,
+			}
+			r.Entries = append(r.Entries, Entry{Cast: c})
+		}
+
+		c.End = e.LocalTime
+		c.Duration = e.Duration
+		c.Cancelled = !e.Complete
+		c.Reset = e.Reset
+	case *evtc.WeaponSwapEvent:
+		if e.Source == nil {
+			return
+		}
+
+		r := b.rotation(e.Source)
+		r.Entries = append(r.Entries, Entry{Swap: &WeaponSwap{
+			Time:      e.LocalTime,
+			WeaponSet: e.WeaponSet,
+		}})
+	}
+}
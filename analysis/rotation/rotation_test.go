@@ -0,0 +1,168 @@
+package rotation
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+const (
+	player = 0x10
+	other  = 0x11
+
+	autoAttack = 1000
+	skill2     = 1001
+	skill3     = 1002
+)
+
+// describe formats an entry for comparison, with times in milliseconds and
+// "?" for a time that was not recorded.
+func describe(e Entry) string {
+	ms := func(t time.Time) string {
+		if t.IsZero() {
+			return "?"
+		}
+		return fmt.Sprint(t.Sub(evtctest.At(0)).Milliseconds())
+	}
+
+	if e.Swap != nil {
+		return fmt.Sprintf("swap to %d at %s", e.Swap.WeaponSet, ms(e.Swap.Time))
+	}
+
+	c := e.Cast
+	s := fmt.Sprintf("%d from %s to %s", c.SkillID, ms(c.Start), ms(c.End))
+	if c.Quickness {
+		s += " quickness"
+	}
+	if c.Cancelled {
+		s += " cancelled"
+	}
+	if c.Reset {
+		s += " reset"
+	}
+	return s
+}
+
+func TestBuild(t *testing.T) {
+	ms := time.Millisecond
+
+	tests := []struct {
+		name     string
+		build    func(l *evtctest.Log)
+		want     []string
+		timeLost time.Duration
+	}{
+		{
+			name: "casts and swaps",
+			build: func(l *evtctest.Log) {
+				l.Activation(1000, player, autoAttack, 1, 500*ms) // ACTV_NORMAL
+				l.Activation(1500, player, autoAttack, 3, 500*ms) // ACTV_CANCEL_FIRE
+				l.StateChange(1600, 11, player, 5, 0)             // CBTS_WEAPSWAP
+				l.Activation(1700, player, skill2, 2, 800*ms)     // ACTV_QUICKNESS
+				l.Activation(2500, player, skill2, 5, 800*ms)     // ACTV_RESET
+			},
+			want: []string{
+				"1000 from 1000 to 1500",
+				"swap to 5 at 1600",
+				"1001 from 1700 to 2500 quickness reset",
+			},
+		},
+		{
+			name: "cancelled cast",
+			build: func(l *evtctest.Log) {
+				l.Activation(1000, player, skill2, 1, 1000*ms)
+				l.Activation(1300, player, skill2, 4, 300*ms) // ACTV_CANCEL_CANCEL
+				l.Activation(1400, player, skill3, 1, 500*ms)
+				l.Activation(1600, player, skill3, 4, 200*ms)
+			},
+			want: []string{
+				"1001 from 1000 to 1300 cancelled",
+				"1002 from 1400 to 1600 cancelled",
+			},
+			timeLost: 500 * ms,
+		},
+		{
+			name: "completion without a start",
+			build: func(l *evtctest.Log) {
+				l.Activation(1000, player, skill2, 3, 800*ms)
+				l.Activation(1100, player, autoAttack, 1, 500*ms)
+			},
+			want: []string{
+				"1001 from ? to 1000",
+				"1000 from 1100 to ?",
+			},
+		},
+		{
+			name: "mismatched completion",
+			build: func(l *evtctest.Log) {
+				l.Activation(1000, player, skill2, 1, 800*ms)
+				l.Activation(1200, player, skill3, 3, 200*ms)
+			},
+			want: []string{
+				"1001 from 1000 to ?",
+				"1002 from ? to 1200",
+			},
+		},
+		{
+			name: "new activation abandons the pending cast",
+			build: func(l *evtctest.Log) {
+				l.Activation(1000, player, skill2, 1, 800*ms)
+				l.Activation(1200, player, skill3, 1, 200*ms)
+				l.Activation(1400, player, skill2, 3, 800*ms)
+			},
+			want: []string{
+				"1001 from 1000 to ?",
+				"1002 from 1200 to ?",
+				"1001 from ? to 1400",
+			},
+		},
+		{
+			name: "agents are tracked separately",
+			build: func(l *evtctest.Log) {
+				l.Activation(1000, player, skill2, 1, 800*ms)
+				l.Activation(1100, other, skill3, 1, 200*ms)
+				l.Activation(1300, other, skill3, 3, 200*ms)
+				l.Activation(1800, player, skill2, 3, 800*ms)
+			},
+			want: []string{
+				"1001 from 1000 to 1800",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &evtctest.Log{}
+			l.Player(player, evtc.Guardian, 0, "Player", ":Player.1234", 1)
+			l.Player(other, evtc.Warrior, 0, "Other", ":Other.1234", 1)
+			tt.build(l)
+			chain := l.Parse(t)
+
+			var r *Rotation
+			for _, rot := range Build(chain) {
+				if rot.Agent.Address() == player {
+					r = rot
+				}
+			}
+			if r == nil {
+				t.Fatal("no rotation for the player")
+			}
+
+			var got []string
+			for _, e := range r.Entries {
+				got = append(got, describe(e))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Entries =\n%q\nwant\n%q", got, tt.want)
+			}
+
+			if lost := r.TimeLost(); lost != tt.timeLost {
+				t.Errorf("TimeLost = %v; want %v", lost, tt.timeLost)
+			}
+		})
+	}
+}
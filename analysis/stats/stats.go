@@ -0,0 +1,262 @@
+// Package stats totals the damage dealt in a log by source, target, and
+// skill.
+package stats
+
+import (
+	"time"
+
+	"github.com/BenLubar/evtc"
+)
+
+// Damage is a damage total.
+type Damage struct {
+	// Power is direct damage, including damage absorbed by barrier.
+	Power int
+	// Condition is damage from buffs, such as bleeding or burning.
+	Condition int
+	// Barrier is the part of Power that was absorbed by barrier.
+	Barrier int
+
+	// Hits is the number of direct hits that connected.
+	Hits      int
+	Crits     int
+	Flanks    int
+	Glances   int
+	Blocked   int
+	Evaded    int
+	Missed    int
+	Invulned  int
+	Interrupt int
+
+	// Ticks is the number of times a buff dealt damage.
+	Ticks int
+}
+
+// Total returns the sum of power and condition damage.
+func (d Damage) Total() int {
+	return d.Power + d.Condition
+}
+
+// CritRate returns the fraction of hits that were critical hits.
+func (d Damage) CritRate() float64 {
+	return rate(d.Crits, d.Hits)
+}
+
+// FlankRate returns the fraction of hits that were from behind or beside
+// the target.
+func (d Damage) FlankRate() float64 {
+	return rate(d.Flanks, d.Hits)
+}
+
+// GlanceRate returns the fraction of hits that were glancing blows.
+func (d Damage) GlanceRate() float64 {
+	return rate(d.Glances, d.Hits)
+}
+
+// DPS returns the total damage per second over the given window.
+func (d Damage) DPS(window time.Duration) float64 {
+	if window <= 0 {
+		return 0
+	}
+	return float64(d.Total()) / window.Seconds()
+}
+
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func (d *Damage) add(o Damage) {
+	d.Power += o.Power
+	d.Condition += o.Condition
+	d.Barrier += o.Barrier
+	d.Hits += o.Hits
+	d.Crits += o.Crits
+	d.Flanks += o.Flanks
+	d.Glances += o.Glances
+	d.Blocked += o.Blocked
+	d.Evaded += o.Evaded
+	d.Missed += o.Missed
+	d.Invulned += o.Invulned
+	d.Interrupt += o.Interrupt
+	d.Ticks += o.Ticks
+}
+
+// Key identifies the finest grain of a damage total.
+type Key struct {
+	// Source is the agent credited with the damage. Damage dealt by
+	// minions, pets, and clones is credited to their owner.
+	Source *evtc.Agent
+	// Minion is the agent that actually dealt the damage, or nil if the
+	// damage was dealt by Source itself.
+	Minion *evtc.Agent
+	Target *evtc.Agent
+	Skill  int
+}
+
+// Report is a damage breakdown over a window of time.
+type Report struct {
+	Start time.Time
+	End   time.Time
+
+	Totals map[Key]*Damage
+}
+
+// NewReport returns an empty report for events between start (inclusive) and
+// end (exclusive). A zero start or end leaves that side of the window open.
+func NewReport(start, end time.Time) *Report {
+	return &Report{
+		Start:  start,
+		End:    end,
+		Totals: make(map[Key]*Damage),
+	}
+}
+
+// Compute totals the damage in chain between start and end. A zero start
+// defaults to the log start, and a zero end defaults to the last event.
+func Compute(chain *evtc.EventChain, start, end time.Time) *Report {
+	if start.IsZero() {
+		start = chain.Start()
+	}
+
+	r := NewReport(start, end)
+	for _, e := range chain.Events {
+		r.Add(e)
+	}
+
+	if end.IsZero() && len(chain.Events) != 0 {
+		r.End, _ = chain.Events[len(chain.Events)-1].Time()
+	}
+
+	return r
+}
+
+// Duration returns the length of the report's window.
+func (r *Report) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+func (r *Report) total(source, target *evtc.Agent, skill int) *Damage {
+	key := Key{
+		Source: source.Owner(),
+		Target: target,
+		Skill:  skill,
+	}
+	if key.Source != source {
+		key.Minion = source
+	}
+
+	d, ok := r.Totals[key]
+	if !ok {
+		d = &Damage{}
+		r.Totals[key] = d
+	}
+	return d
+}
+
+func (r *Report) inWindow(t time.Time) bool {
+	return (r.Start.IsZero() || !t.Before(r.Start)) && (r.End.IsZero() || t.Before(r.End))
+}
+
+// Add totals a single event. Events other than DirectDamageEvent and
+// BuffDamageEvent, and events outside the window, are ignored.
+func (r *Report) Add(e evtc.Event) {
+	switch e := e.(type) {
+	case *evtc.DirectDamageEvent:
+		if !r.inWindow(e.LocalTime) {
+			return
+		}
+
+		d := r.total(e.Source, e.Target, e.SkillID)
+		d.Power += e.Damage
+		d.Barrier += e.Barrier
+
+		switch {
+		case e.Blocked:
+			d.Blocked++
+		case e.Evaded:
+			d.Evaded++
+		case e.Missed:
+			d.Missed++
+		case e.Invulnerable:
+			d.Invulned++
+		default:
+			d.Hits++
+			if e.Critical {
+				d.Crits++
+			}
+			if e.Glancing {
+				d.Glances++
+			}
+			if e.Interrupt {
+				d.Interrupt++
+			}
+			if e.Flanking {
+				d.Flanks++
+			}
+		}
+	case *evtc.BuffDamageEvent:
+		if !r.inWindow(e.LocalTime) || !e.Success {
+			return
+		}
+
+		d := r.total(e.Source, e.Target, e.SkillID)
+		d.Condition += e.Damage
+		d.Ticks++
+	}
+}
+
+// Filter returns the sum of every total whose key matches f.
+func (r *Report) Filter(f func(Key) bool) Damage {
+	var total Damage
+	for k, d := range r.Totals {
+		if f(k) {
+			total.add(*d)
+		}
+	}
+	return total
+}
+
+// Total returns the sum of every total in the report.
+func (r *Report) Total() Damage {
+	return r.Filter(func(Key) bool { return true })
+}
+
+// BySource returns the damage dealt by each source agent, with minion damage
+// included in its owner's total.
+func (r *Report) BySource() map[*evtc.Agent]Damage {
+	return r.byAgent(func(k Key) *evtc.Agent { return k.Source })
+}
+
+// ByTarget returns the damage taken by each target agent.
+func (r *Report) ByTarget() map[*evtc.Agent]Damage {
+	return r.byAgent(func(k Key) *evtc.Agent { return k.Target })
+}
+
+func (r *Report) byAgent(agent func(Key) *evtc.Agent) map[*evtc.Agent]Damage {
+	m := make(map[*evtc.Agent]Damage)
+	for k, d := range r.Totals {
+		total := m[agent(k)]
+		total.add(*d)
+		m[agent(k)] = total
+	}
+	return m
+}
+
+// BySkill returns the damage dealt by each skill.
+func (r *Report) BySkill() map[int]Damage {
+	m := make(map[int]Damage)
+	for k, d := range r.Totals {
+		total := m[k.Skill]
+		total.add(*d)
+		m[k.Skill] = total
+	}
+	return m
+}
+
+// DPS returns the damage per second of d over the report's window.
+func (r *Report) DPS(d Damage) float64 {
+	return d.DPS(r.Duration())
+}
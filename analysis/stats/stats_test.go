@@ -0,0 +1,137 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+const (
+	player = 0x10
+	minion = 0x11
+	boss   = 0x20
+
+	sword    = 9143
+	clone    = 10186
+	bleeding = 736
+)
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name       string
+		build      func(l *evtctest.Log)
+		start, end uint64
+
+		want   Damage
+		minion Damage
+		dps    float64
+	}{
+		{
+			name: "hit results",
+			build: func(l *evtctest.Log) {
+				l.Damage(1000, player, boss, sword, 100, 0)
+				l.Damage(1100, player, boss, sword, 200, 1) // crit
+				l.Damage(1200, player, boss, sword, 50, 2)  // glance
+				l.Damage(1300, player, boss, sword, 0, 3)   // block
+				l.Damage(1400, player, boss, sword, 0, 4)   // evade
+				l.Damage(1500, player, boss, sword, 0, 6)   // invulnerable
+				l.Damage(1600, player, boss, sword, 0, 7)   // blind
+				l.Damage(1700, player, boss, sword, 70, 5)  // interrupt
+			},
+			start: 1000,
+			end:   2000,
+			want: Damage{
+				Power:     420,
+				Hits:      4,
+				Crits:     1,
+				Glances:   1,
+				Blocked:   1,
+				Evaded:    1,
+				Invulned:  1,
+				Missed:    1,
+				Interrupt: 1,
+			},
+			dps: 420,
+		},
+		{
+			name: "minion damage is credited to its owner",
+			build: func(l *evtctest.Log) {
+				l.Damage(1000, player, boss, sword, 100, 0)
+				l.Damage(1500, minion, boss, clone, 300, 1)
+			},
+			start:  1000,
+			end:    3000,
+			want:   Damage{Power: 400, Hits: 2, Crits: 1},
+			minion: Damage{Power: 300, Hits: 1, Crits: 1},
+			dps:    200,
+		},
+		{
+			name: "condition damage",
+			build: func(l *evtctest.Log) {
+				l.ConditionDamage(1000, player, boss, bleeding, 40)
+				l.ConditionDamage(2000, player, boss, bleeding, 40)
+				l.Damage(2000, player, boss, sword, 20, 0)
+			},
+			start: 1000,
+			end:   3000,
+			want:  Damage{Power: 20, Condition: 80, Hits: 1, Ticks: 2},
+			dps:   50,
+		},
+		{
+			name: "events outside the window",
+			build: func(l *evtctest.Log) {
+				l.Damage(500, player, boss, sword, 1000, 0)
+				l.Damage(1000, player, boss, sword, 10, 0)
+				l.Damage(1999, player, boss, sword, 10, 0)
+				l.Damage(2000, player, boss, sword, 1000, 0)
+			},
+			start: 1000,
+			end:   2000,
+			want:  Damage{Power: 20, Hits: 2},
+			dps:   20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &evtctest.Log{}
+			l.Player(player, evtc.Mesmer, 0, "Player", ":Player.1234", 1)
+			l.Minion(minion, 8108, "Clone", player)
+			l.NPC(boss, 15438, "Boss")
+			tt.build(l)
+			chain := l.Parse(t)
+
+			agents := make(map[uint64]*evtc.Agent)
+			for _, a := range chain.Agents() {
+				agents[a.Address()] = a
+			}
+
+			r := Compute(chain, evtctest.At(tt.start), evtctest.At(tt.end))
+
+			bySource := r.BySource()
+			if got := bySource[agents[player]]; got != tt.want {
+				t.Errorf("BySource()[player] =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if _, ok := bySource[agents[minion]]; ok {
+				t.Error("BySource() has a total for the minion")
+			}
+			if got := r.ByTarget()[agents[boss]]; got != tt.want {
+				t.Errorf("ByTarget()[boss] =\n%+v\nwant\n%+v", got, tt.want)
+			}
+
+			gotMinion := r.Filter(func(k Key) bool { return k.Minion == agents[minion] })
+			if gotMinion != tt.minion {
+				t.Errorf("minion damage =\n%+v\nwant\n%+v", gotMinion, tt.minion)
+			}
+
+			if got := r.DPS(r.Total()); got != tt.dps {
+				t.Errorf("DPS = %v; want %v", got, tt.dps)
+			}
+			if r.Duration() != time.Duration(tt.end-tt.start)*time.Millisecond {
+				t.Errorf("Duration = %v", r.Duration())
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package evtc
+
+import (
+	"math"
+	"time"
+)
+
+// BuffCategory is the category arcdps assigns to a buff. Only the boon and
+// condition categories have kept the same value across game builds; other
+// values are passed through as-is.
+type BuffCategory uint8
+
+const (
+	BoonCategory      BuffCategory = 0
+	ConditionCategory BuffCategory = 2
+)
+
+// BuffStackType is how the game combines multiple stacks of a buff.
+type BuffStackType uint8
+
+const (
+	BuffStackIntensityConditionalLoss BuffStackType = iota
+	BuffStackQueue
+	BuffStackCappedDuration
+	BuffStackRegeneration
+	BuffStackIntensity
+	BuffStackForce
+)
+
+// Intensity reports whether every stack of a buff with this stack type is
+// in effect at once.
+func (t BuffStackType) Intensity() bool {
+	return t == BuffStackIntensity || t == BuffStackIntensityConditionalLoss
+}
+
+// BuffInfo is the definition of a buff, as recorded by arcdps.
+type BuffInfo struct {
+	ID        int
+	Name      string
+	Category  BuffCategory
+	StackType BuffStackType
+
+	// MaxStacks is the number of stacks that can be in effect at once.
+	MaxStacks int
+	// DurationCap is the maximum duration of the buff, or 0 if there is
+	// no cap.
+	DurationCap time.Duration
+
+	Invulnerability bool
+	Invert          bool
+	Resistance      bool
+
+	Formulas []BuffFormula
+}
+
+// BuffFormula is one of the attribute modifiers a buff applies. The meaning
+// of Type and the attributes are game data that arcdps does not document.
+type BuffFormula struct {
+	Type      int
+	Attr1     int
+	Attr2     int
+	Param1    float32
+	Param2    float32
+	Param3    float32
+	TraitSrc  int
+	TraitSelf int
+	BuffSrc   int
+	BuffSelf  int
+
+	NPC    bool
+	Player bool
+	Break  bool
+}
+
+// Buffs returns the buff definitions recorded in the log, keyed by buff ID.
+// Logs from arcdps versions that did not record buff definitions return an
+// empty map.
+func (c *EventChain) Buffs() map[int]BuffInfo {
+	buffs := make(map[int]BuffInfo, len(c.buffs))
+	for id, info := range c.buffs {
+		b := *info
+		b.Formulas = append([]BuffFormula(nil), info.Formulas...)
+		buffs[int(id)] = b
+	}
+	return buffs
+}
+
+// buffInfo returns the definition of a buff, adding it to the table if it
+// is not already there.
+func (c *EventChain) buffInfo(id uint32) *BuffInfo {
+	if info, ok := c.buffs[id]; ok {
+		return info
+	}
+
+	info := &BuffInfo{
+		ID:   int(id),
+		Name: c.skills[id],
+	}
+	if c.buffs == nil {
+		c.buffs = make(map[uint32]*BuffInfo)
+	}
+	c.buffs[id] = info
+	c.buffIDs = append(c.buffIDs, id)
+	return info
+}
+
+// CBTS_BUFFINFO, skillid = buffid, overstack_value = duration cap,
+// src_master_instid = max stacks, is_flanking = invuln, is_shields = invert,
+// is_offcycle = category, pad61 = stacking type, pad62 = resistance
+func parseBuffInfo(chain *EventChain, event cbtevent1) {
+	info := chain.buffInfo(event.SkillID)
+	info.Category = BuffCategory(event.IsOffCycle)
+	info.StackType = BuffStackType(event.Pad61_64)
+	info.MaxStacks = int(event.SrcMasterInstID)
+	info.DurationCap = time.Duration(event.OverstackValue) * time.Millisecond
+	info.Invulnerability = event.IsFlanking != 0
+	info.Invert = event.IsShields != 0
+	info.Resistance = uint8(event.Pad61_64>>8) != 0
+}
+
+// CBTS_BUFFFORMULA, skillid = buffid, time through buff_dmg = float[8] type,
+// attr1, attr2, param1, param2, param3, trait_src, trait_self,
+// src_instid through dst_master_instid = float[2] buff_src, buff_self,
+// is_flanking = !npc, is_shields = !player, is_offcycle = break
+func parseBuffFormula(chain *EventChain, event cbtevent1) {
+	f := buffFormulaFloats(event)
+	info := chain.buffInfo(event.SkillID)
+	info.Formulas = append(info.Formulas, BuffFormula{
+		Type:      int(f[0]),
+		Attr1:     int(f[1]),
+		Attr2:     int(f[2]),
+		Param1:    f[3],
+		Param2:    f[4],
+		Param3:    f[5],
+		TraitSrc:  int(f[6]),
+		TraitSelf: int(f[7]),
+		BuffSrc:   int(math.Float32frombits(uint32(event.SrcInstID) | uint32(event.DstInstID)<<16)),
+		BuffSelf:  int(math.Float32frombits(uint32(event.SrcMasterInstID) | uint32(event.DstMasterInstID)<<16)),
+		NPC:       event.IsFlanking == 0,
+		Player:    event.IsShields == 0,
+		Break:     event.IsOffCycle != 0,
+	})
+}
+
+func buffFormulaFloats(event cbtevent1) [8]float32 {
+	return [8]float32{
+		math.Float32frombits(uint32(event.Time)),
+		math.Float32frombits(uint32(event.Time >> 32)),
+		math.Float32frombits(uint32(event.SrcAgent)),
+		math.Float32frombits(uint32(event.SrcAgent >> 32)),
+		math.Float32frombits(uint32(event.DstAgent)),
+		math.Float32frombits(uint32(event.DstAgent >> 32)),
+		math.Float32frombits(uint32(event.Value)),
+		math.Float32frombits(uint32(event.BuffDmg)),
+	}
+}
+
+func encodeBuffInfo(info *BuffInfo, tick uint64) []cbtevent1 {
+	events := []cbtevent1{{
+		Time:            tick,
+		SkillID:         uint32(info.ID),
+		OverstackValue:  uint32(info.DurationCap / time.Millisecond),
+		SrcMasterInstID: uint16(info.MaxStacks),
+		IsFlanking:      boolByte(info.Invulnerability),
+		IsShields:       boolByte(info.Invert),
+		IsOffCycle:      uint8(info.Category),
+		Pad61_64:        uint32(info.StackType) | uint32(boolByte(info.Resistance))<<8,
+		IsStateChange:   30,
+	}}
+
+	for _, f := range info.Formulas {
+		bits := func(v float32) uint64 {
+			return uint64(math.Float32bits(v))
+		}
+		buffSrc := math.Float32bits(float32(f.BuffSrc))
+		buffSelf := math.Float32bits(float32(f.BuffSelf))
+
+		events = append(events, cbtevent1{
+			Time:            bits(float32(f.Type)) | bits(float32(f.Attr1))<<32,
+			SrcAgent:        bits(float32(f.Attr2)) | bits(f.Param1)<<32,
+			DstAgent:        bits(f.Param2) | bits(f.Param3)<<32,
+			Value:           int32(math.Float32bits(float32(f.TraitSrc))),
+			BuffDmg:         int32(math.Float32bits(float32(f.TraitSelf))),
+			SkillID:         uint32(info.ID),
+			SrcInstID:       uint16(buffSrc),
+			DstInstID:       uint16(buffSrc >> 16),
+			SrcMasterInstID: uint16(buffSelf),
+			DstMasterInstID: uint16(buffSelf >> 16),
+			IsFlanking:      boolByte(!f.NPC),
+			IsShields:       boolByte(!f.Player),
+			IsOffCycle:      boolByte(f.Break),
+			IsStateChange:   31,
+		})
+	}
+
+	return events
+}
@@ -89,3 +89,14 @@ type cbtevent1 struct {
 	IsOffCycle      uint8
 	Pad61_64        uint32
 }
+
+// hasTime reports whether the Time field of e is a timestamp. Some
+// statechanges use it to hold other data.
+func (e *cbtevent1) hasTime() bool {
+	switch e.IsStateChange {
+	case 31, 32: // CBTS_BUFFFORMULA, CBTS_SKILLINFO
+		return false
+	default:
+		return true
+	}
+}
@@ -1,9 +1,9 @@
 package evtc
 
 import (
+	"sync"
 	"time"
 
-	"github.com/pkg/errors"
 	"golang.org/x/text/language"
 )
 
@@ -11,10 +11,25 @@ type EventChain struct {
 	agents map[uint64]*Agent
 	skills map[uint32]string
 
+	// agent and skill table order, as read from the file
+	agentList []*Agent
+	skillIDs  []uint32
+
+	// buff definitions, in the order they were first seen
+	buffs   map[uint32]*BuffInfo
+	buffIDs []uint32
+
+	// skill definitions, in the order they were first seen
+	skillInfo    map[uint32]*SkillInfo
+	skillInfoIDs []uint32
+
 	serverTime time.Time
 	localTime  time.Time
 	timeOffset time.Duration
 
+	indexLock sync.Mutex
+	index     *eventIndex
+
 	ArcDPSVersion string
 	BuildID       int
 	BossSpecies   int
@@ -24,35 +39,125 @@ type EventChain struct {
 	Events        []Event
 	WorldID       uint16
 	MapID         uint16
+
+	// Truncated is set if the log ended early and was parsed with
+	// AllowTruncated.
+	Truncated bool
 }
 
-func makeEventChain(h header, agents map[uint64]*wrappedAgent, skills map[uint32]string, events []cbtevent1) (*EventChain, error) {
+func newEventChain(h header, agents []*wrappedAgent, skills []skill) *EventChain {
 	chain := &EventChain{
-		agents: make(map[uint64]*Agent),
-		skills: skills,
+		agents:    make(map[uint64]*Agent, len(agents)),
+		skills:    wrapSkills(skills),
+		agentList: make([]*Agent, len(agents)),
+		skillIDs:  make([]uint32, len(skills)),
 
 		ArcDPSVersion: string(h.Date[:]),
 	}
 
 	chain.BossSpecies = int(h.Boss)
-	for addr, wrapped := range agents {
+	for i, wrapped := range agents {
 		if wrapped.speciesID == h.Boss {
 			chain.BossName = wrapped.charName
 		}
 
-		chain.agents[addr] = &Agent{
+		a := &Agent{
 			wrapped: wrapped,
 			chain:   chain,
 		}
+		chain.agents[wrapped.Addr] = a
+		chain.agentList[i] = a
+	}
+
+	for i, s := range skills {
+		chain.skillIDs[i] = s.ID
 	}
 
-	for _, event := range events {
-		if e, err := parseEvent(chain, event); err != nil {
-			return nil, errors.Wrap(err, "evtc: failed to parse event")
-		} else if e != nil {
-			chain.Events = append(chain.Events, e)
+	return chain
+}
+
+// localTimeAt converts an arcdps timestamp to the local clock.
+func (c *EventChain) localTimeAt(tick uint64) time.Time {
+	return c.localTime.Add(time.Duration(tick)*time.Millisecond + c.timeOffset)
+}
+
+// serverTimeAt converts an arcdps timestamp to the server clock.
+func (c *EventChain) serverTimeAt(tick uint64) time.Time {
+	return c.serverTime.Add(time.Duration(tick)*time.Millisecond + c.timeOffset)
+}
+
+// Start returns the local time of the log start event. Events that happen
+// before the log start event are relative to the zero time instead.
+func (c *EventChain) Start() time.Time {
+	return c.localTime
+}
+
+// Agents returns every agent in the log, in the order arcdps recorded them.
+func (c *EventChain) Agents() []*Agent {
+	return append([]*Agent(nil), c.agentList...)
+}
+
+// Players returns the player agents in the log, in the order arcdps
+// recorded them.
+func (c *EventChain) Players() []*Agent {
+	return c.filterAgents(func(a *Agent) bool {
+		_, ok := a.Player()
+		return ok
+	})
+}
+
+// NPCs returns the NPC agents in the log, in the order arcdps recorded them.
+func (c *EventChain) NPCs() []*Agent {
+	return c.filterAgents(func(a *Agent) bool {
+		_, ok := a.NPC()
+		return ok
+	})
+}
+
+// Gadgets returns the gadget agents in the log, in the order arcdps
+// recorded them.
+func (c *EventChain) Gadgets() []*Agent {
+	return c.filterAgents((*Agent).IsGadget)
+}
+
+func (c *EventChain) filterAgents(f func(*Agent) bool) []*Agent {
+	var agents []*Agent
+	for _, a := range c.agentList {
+		if f(a) {
+			agents = append(agents, a)
 		}
 	}
+	return agents
+}
+
+// AgentByAddress returns the agent with the given address, or nil if there
+// is no such agent.
+func (c *EventChain) AgentByAddress(addr uint64) *Agent {
+	return c.agents[addr]
+}
+
+// Skills returns a copy of the skill table, mapping skill IDs to names.
+func (c *EventChain) Skills() map[int]string {
+	skills := make(map[int]string, len(c.skills))
+	for id, name := range c.skills {
+		skills[int(id)] = name
+	}
+	return skills
+}
+
+// SkillIDs returns the IDs in the skill table, in the order arcdps recorded
+// them. The custom skills that Skills adds, such as Dodge, are only listed
+// if the log's skill table includes them.
+func (c *EventChain) SkillIDs() []int {
+	ids := make([]int, len(c.skillIDs))
+	for i, id := range c.skillIDs {
+		ids[i] = int(id)
+	}
+	return ids
+}
 
-	return chain, nil
+// SkillName returns the name of the skill with the given ID, or an empty
+// string if the skill is not in the skill table.
+func (c *EventChain) SkillName(id int) string {
+	return c.skills[uint32(id)]
 }
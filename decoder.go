@@ -0,0 +1,136 @@
+package evtc
+
+import (
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Decoder reads the events of an EVTC file one at a time, without keeping
+// the whole log in memory.
+type Decoder struct {
+	r        *offsetReader
+	revision revision
+	chain    *EventChain
+	tracker  agentTracker
+	opts     options
+	closer   io.Closer
+
+	lastTick  uint64
+	sawLogEnd bool
+	done      bool
+}
+
+// NewDecoder reads the header, agent table, and skill table from r and
+// returns a Decoder positioned at the first event.
+func NewDecoder(r io.Reader, opts ...Option) (*Decoder, error) {
+	or := &offsetReader{r: r}
+	h, rev, agents, skills, err := parseHeader(or)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedAgents := wrapAgents(agents)
+
+	return &Decoder{
+		r:        or,
+		revision: rev,
+		chain:    newEventChain(h, wrappedAgents, skills),
+		tracker:  newAgentTracker(wrappedAgents),
+		opts:     makeOptions(opts),
+	}, nil
+}
+
+// Close closes the file opened by OpenFile or NewAutoDecoder. It does not
+// close the io.Reader passed to NewDecoder or NewAutoDecoder.
+func (d *Decoder) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+	return d.closer.Close()
+}
+
+// Header returns the EventChain being decoded. Its agent and skill tables
+// are complete as soon as the Decoder is created. Fields that arcdps records
+// as events, such as PointOfView and Language, are filled in as Next reaches
+// them, and agent awareness is updated incrementally. Events is left empty.
+func (d *Decoder) Header() *EventChain {
+	return d.chain
+}
+
+// Next returns the next event in the log. It returns io.EOF when there are
+// no more events.
+//
+// If the decoder was created with AllowTruncated and the log ends early,
+// Next returns a synthesized LogEndEvent (unless the log already had one)
+// before returning io.EOF.
+func (d *Decoder) Next() (Event, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+
+	for {
+		offset := d.r.offset
+		event, err := d.revision.readEvent(d.r)
+		if d.opts.allowTruncated {
+			if _, ok := err.(*TruncatedError); ok || (err == io.EOF && !d.sawLogEnd) {
+				return d.truncate()
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if event.hasTime() {
+			d.tracker.update(event)
+			if event.Time > d.lastTick {
+				d.lastTick = event.Time
+			}
+		}
+		if event.IsStateChange == 10 { // CBTS_LOGEND
+			d.sawLogEnd = true
+		}
+
+		e, err := parseEvent(d.chain, event)
+		if err != nil {
+			return nil, errors.Wrap(err, "evtc: failed to parse event")
+		}
+
+		if u, ok := e.(*UnknownEvent); ok {
+			switch d.opts.unknownEvents {
+			case SkipUnknownEvents:
+				continue
+			case RejectUnknownEvents:
+				return nil, &UnknownEventError{Event: u, Offset: offset}
+			}
+		}
+
+		if e != nil {
+			return e, nil
+		}
+	}
+}
+
+// truncate marks the log as truncated and returns a LogEndEvent at the time
+// of the last event that was read.
+func (d *Decoder) truncate() (Event, error) {
+	d.chain.Truncated = true
+	d.done = true
+
+	if d.sawLogEnd {
+		return nil, io.EOF
+	}
+
+	local, server := d.chain.localTimeAt(d.lastTick), d.chain.serverTimeAt(d.lastTick)
+	return &LogEndEvent{
+		BaseEvent: BaseEvent{
+			Type:       "LogEnd",
+			LocalTime:  local,
+			ServerTime: server,
+			Tick:       d.lastTick,
+		},
+		RealServerTime: server.Truncate(time.Second).UTC(),
+		RealLocalTime:  local.Truncate(time.Second).UTC(),
+	}, nil
+}
@@ -0,0 +1,196 @@
+package evtc
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func float32Bits(lo, hi float32) uint64 {
+	return uint64(math.Float32bits(lo)) | uint64(math.Float32bits(hi))<<32
+}
+
+type definitionTest struct {
+	name string
+	// at is the time of the definition events that carry one.
+	at     uint64
+	events []cbtevent1
+	want   interface{}
+}
+
+// TestDefinitions decodes the statechanges that define buffs and skills and
+// encodes the definitions again.
+func TestDefinitions(t *testing.T) {
+	buffSrc := math.Float32bits(1122)
+	buffSelf := math.Float32bits(30328)
+
+	// roundTrip is a test case for a definition that is encoded at time 0.
+	roundTrip := func(name string, want interface{}) definitionTest {
+		tt := definitionTest{name: name, want: want}
+		switch want := want.(type) {
+		case BuffInfo:
+			tt.events = encodeBuffInfo(&want, 0)
+		case SkillInfo:
+			tt.events = encodeSkillInfo(&want, 0)
+		}
+		return tt
+	}
+
+	tests := []definitionTest{
+		{
+			name: "buff",
+			at:   1000,
+			events: []cbtevent1{
+				{
+					Time:            1000,
+					SkillID:         740,
+					OverstackValue:  30000,
+					SrcMasterInstID: 25,
+					IsFlanking:      1,
+					IsShields:       0,
+					IsOffCycle:      uint8(BoonCategory),
+					Pad61_64:        0x0104, // pad61 = stacking type 4, pad62 = resistance
+					IsStateChange:   30,
+				},
+				{
+					Time:            float32Bits(17, 4),
+					SrcAgent:        float32Bits(2, 30),
+					DstAgent:        float32Bits(0.5, -1.25),
+					Value:           int32(math.Float32bits(2143)),
+					BuffDmg:         int32(math.Float32bits(2144)),
+					SkillID:         740,
+					SrcInstID:       uint16(buffSrc),
+					DstInstID:       uint16(buffSrc >> 16),
+					SrcMasterInstID: uint16(buffSelf),
+					DstMasterInstID: uint16(buffSelf >> 16),
+					IsFlanking:      0,
+					IsShields:       1,
+					IsOffCycle:      1,
+					IsStateChange:   31,
+				},
+			},
+			want: BuffInfo{
+				ID:              740,
+				Name:            "Might",
+				Category:        BoonCategory,
+				StackType:       BuffStackIntensity,
+				MaxStacks:       25,
+				DurationCap:     30 * time.Second,
+				Invulnerability: true,
+				Invert:          false,
+				Resistance:      true,
+				Formulas: []BuffFormula{{
+					Type:      17,
+					Attr1:     4,
+					Attr2:     2,
+					Param1:    30,
+					Param2:    0.5,
+					Param3:    -1.25,
+					TraitSrc:  2143,
+					TraitSelf: 2144,
+					BuffSrc:   1122,
+					BuffSelf:  30328,
+					NPC:       true,
+					Player:    false,
+					Break:     true,
+				}},
+			},
+		},
+		roundTrip("queued buff", BuffInfo{
+			ID:          1187,
+			Category:    BoonCategory,
+			StackType:   BuffStackQueue,
+			MaxStacks:   9,
+			DurationCap: 5 * time.Second,
+		}),
+		roundTrip("buff with several formulas", BuffInfo{
+			ID:              762,
+			Category:        BuffCategory(8),
+			StackType:       BuffStackForce,
+			MaxStacks:       1,
+			Invulnerability: true,
+			Invert:          true,
+			Formulas: []BuffFormula{
+				{Type: 1, Attr1: 2, Param1: 100, BuffSrc: 5, NPC: true, Player: true},
+				{Type: 3, Attr2: 9, Param3: 0.25, TraitSelf: 1000, Break: true},
+			},
+		}),
+		{
+			name: "skill",
+			at:   1000,
+			events: []cbtevent1{
+				{
+					Time:          float32Bits(20, 1200),
+					SrcAgent:      float32Bits(180, 0.75),
+					SkillID:       5491,
+					IsStateChange: 32,
+				},
+				{
+					Time:          1000,
+					SrcAgent:      1,
+					DstAgent:      250,
+					SkillID:       5491,
+					IsStateChange: 33,
+				},
+				{
+					Time:          1000,
+					SrcAgent:      2,
+					DstAgent:      600,
+					SkillID:       5491,
+					IsStateChange: 33,
+				},
+			},
+			want: SkillInfo{
+				ID:          5491,
+				Name:        "Fire",
+				Recharge:    20,
+				Range0:      1200,
+				Range1:      180,
+				TooltipTime: 0.75,
+				Timings: []SkillTiming{
+					{Action: 1, At: 250 * time.Millisecond},
+					{Action: 2, At: 600 * time.Millisecond},
+				},
+			},
+		},
+		roundTrip("skill without timings", SkillInfo{ID: 1, Recharge: 0.5, Range0: 900, Range1: 130, TooltipTime: 1.25}),
+		roundTrip("skill with only a timing", SkillInfo{ID: 12345, Timings: []SkillTiming{{Action: 3, At: time.Second}}}),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := newEventChain(header{}, nil, []skill{
+				{ID: 740, Name: [64]byte{'M', 'i', 'g', 'h', 't'}},
+				{ID: 5491, Name: [64]byte{'F', 'i', 'r', 'e'}},
+			})
+			for _, event := range tt.events {
+				if e, err := parseEvent(chain, event); e != nil || err != nil {
+					t.Fatalf("parseEvent(statechange %d) = %v, %v; want nil, nil", event.IsStateChange, e, err)
+				}
+			}
+
+			var got interface{}
+			var encoded []cbtevent1
+			switch want := tt.want.(type) {
+			case BuffInfo:
+				got = chain.Buffs()[want.ID]
+				encoded = encodeBuffInfo(chain.buffs[uint32(want.ID)], tt.at)
+			case SkillInfo:
+				got, _ = chain.SkillInfo(want.ID)
+				encoded = encodeSkillInfo(chain.skillInfo[uint32(want.ID)], tt.at)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(encoded, tt.events) {
+				t.Errorf("encoded\n%+v\nwant\n%+v", encoded, tt.events)
+			}
+		})
+	}
+
+	if !BuffStackIntensity.Intensity() {
+		t.Error("BuffStackIntensity.Intensity() = false; want true")
+	}
+}
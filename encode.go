@@ -0,0 +1,478 @@
+package evtc
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/language"
+)
+
+// arcdpsID is the source agent of log start and log end events.
+const arcdpsID = 0x637261
+
+// Encode writes chain to w as an uncompressed EVTC file using the revision 1
+// layout.
+//
+// Raw fields that the typed events do not carry are regenerated: instance IDs
+// are taken from the agent table, agents that are not in the agent table are
+// written as 0, and metadata such as PointOfView and Language is written at
+// the start of the event list. Encoding a chain that was itself parsed from
+// the output of Encode produces identical bytes.
+//
+// Agent names are written as they are in the chain; use Agent.SetName and
+// Agent.SetAccount to anonymize a log before encoding it.
+func Encode(w io.Writer, chain *EventChain) error {
+	bw := bufio.NewWriter(w)
+
+	h := header{
+		Revision: 1,
+		Boss:     uint16(chain.BossSpecies),
+	}
+	copy(h.Magic[:], "EVTC")
+	copy(h.Date[:], chain.ArcDPSVersion)
+	if err := errors.Wrap(binary.Write(bw, binary.LittleEndian, &h), "evtc: could not write header"); err != nil {
+		return err
+	}
+
+	agents := make([]agent, len(chain.agentList))
+	for i, a := range chain.agentList {
+		agents[i] = a.wrapped.agent
+	}
+	if err := errors.Wrap(binary.Write(bw, binary.LittleEndian, uint32(len(agents))), "evtc: could not write agent count"); err != nil {
+		return err
+	}
+	if err := errors.Wrap(binary.Write(bw, binary.LittleEndian, agents), "evtc: could not write agents"); err != nil {
+		return err
+	}
+
+	skills := make([]skill, len(chain.skillIDs))
+	for i, id := range chain.skillIDs {
+		skills[i].ID = id
+		copy(skills[i].Name[:len(skills[i].Name)-1], chain.skills[id])
+	}
+	if err := errors.Wrap(binary.Write(bw, binary.LittleEndian, uint32(len(skills))), "evtc: could not write skill count"); err != nil {
+		return err
+	}
+	if err := errors.Wrap(binary.Write(bw, binary.LittleEndian, skills), "evtc: could not write skills"); err != nil {
+		return err
+	}
+
+	events, err := encodeMetadata(chain)
+	if err != nil {
+		return err
+	}
+	for _, e := range chain.Events {
+		event, err := encodeEvent(e)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	if err := errors.Wrap(binary.Write(bw, binary.LittleEndian, events), "evtc: could not write events"); err != nil {
+		return err
+	}
+
+	return errors.Wrap(bw.Flush(), "evtc: could not write events")
+}
+
+// EncodeZip writes chain to w as a zip archive containing a single EVTC file
+// with the given name. This is the format arcdps uses for .zevtc files.
+func EncodeZip(w io.Writer, name string, chain *EventChain) error {
+	zw := zip.NewWriter(w)
+
+	f, err := zw.Create(name)
+	if err != nil {
+		return errors.Wrap(err, "evtc: could not write zip archive")
+	}
+
+	if err = Encode(f, chain); err != nil {
+		return err
+	}
+
+	return errors.Wrap(zw.Close(), "evtc: could not write zip archive")
+}
+
+func encodeMetadata(chain *EventChain) ([]cbtevent1, error) {
+	var tick uint64
+	if len(chain.Events) != 0 {
+		if be, ok := baseEventOf(chain.Events[0]); ok {
+			tick = be.Tick
+		}
+	}
+
+	var events []cbtevent1
+
+	if chain.PointOfView != nil {
+		events = append(events, cbtevent1{
+			Time:          tick,
+			SrcAgent:      chain.PointOfView.wrapped.Addr,
+			IsStateChange: 13,
+		})
+	}
+
+	if chain.Language != language.Und {
+		var id uint64
+		switch chain.Language {
+		case language.English:
+			id = 0
+		case language.Korean:
+			id = 1
+		case language.French:
+			id = 2
+		case language.German:
+			id = 3
+		case language.Spanish:
+			id = 4
+		case language.Chinese:
+			id = 5
+		default:
+			return nil, errors.Errorf("evtc: cannot encode language %v", chain.Language)
+		}
+
+		events = append(events, cbtevent1{
+			Time:          tick,
+			SrcAgent:      id,
+			IsStateChange: 14,
+		})
+	}
+
+	if chain.BuildID != 0 {
+		events = append(events, cbtevent1{
+			Time:          tick,
+			SrcAgent:      uint64(chain.BuildID),
+			IsStateChange: 15,
+		})
+	}
+
+	if chain.WorldID != 0 {
+		events = append(events, cbtevent1{
+			Time:          tick,
+			SrcAgent:      uint64(chain.WorldID),
+			IsStateChange: 16,
+		})
+	}
+
+	if chain.MapID != 0 {
+		events = append(events, cbtevent1{
+			Time:          tick,
+			SrcAgent:      uint64(chain.MapID),
+			IsStateChange: 25,
+		})
+	}
+
+	for _, id := range chain.buffIDs {
+		events = append(events, encodeBuffInfo(chain.buffs[id], tick)...)
+	}
+
+	for _, id := range chain.skillInfoIDs {
+		events = append(events, encodeSkillInfo(chain.skillInfo[id], tick)...)
+	}
+
+	return events, nil
+}
+
+func agentAddr(a *Agent) uint64 {
+	if a == nil {
+		return 0
+	}
+	return a.wrapped.Addr
+}
+
+func agentInstID(a *Agent) uint16 {
+	if a == nil {
+		return 0
+	}
+	return a.wrapped.instanceID
+}
+
+func agentMasterInstID(a *Agent) uint16 {
+	if a == nil {
+		return 0
+	}
+	return agentInstID(a.Master())
+}
+
+func boolByte(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func millis(d time.Duration) int32 {
+	return int32(d / time.Millisecond)
+}
+
+func encodeBaseEvent(e *BaseEvent, statechange uint8) cbtevent1 {
+	return cbtevent1{
+		Time:            e.Tick,
+		SrcAgent:        agentAddr(e.Source),
+		SrcInstID:       agentInstID(e.Source),
+		SrcMasterInstID: agentMasterInstID(e.Source),
+		IsStateChange:   statechange,
+	}
+}
+
+func encodeCommonEvent(e *CommonEvent) cbtevent1 {
+	event := encodeBaseEvent(&e.BaseEvent, 0)
+	event.DstAgent = agentAddr(e.Target)
+	event.DstInstID = agentInstID(e.Target)
+	event.DstMasterInstID = agentMasterInstID(e.Target)
+	event.SkillID = uint32(e.SkillID)
+	event.IsNinety = boolByte(e.Ninety)
+	event.IsFifty = boolByte(e.Fifty)
+	event.IsMoving = boolByte(e.Moving)
+	event.IsFlanking = boolByte(e.Flanking)
+
+	switch {
+	case e.Friend:
+		event.Iff = 0 // IFF_FRIEND
+	case e.Foe:
+		event.Iff = 1 // IFF_FOE
+	default:
+		event.Iff = 2 // IFF_UNKNOWN
+	}
+
+	return event
+}
+
+func encodeVector(x, y, z float32) (uint64, int32) {
+	return uint64(math.Float32bits(x)) | uint64(math.Float32bits(y))<<32, int32(math.Float32bits(z))
+}
+
+func encodeEvent(e Event) (cbtevent1, error) {
+	switch e := e.(type) {
+	case *EnterCombatEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 1)
+		event.DstAgent = uint64(e.Subgroup)
+		return event, nil
+	case *ExitCombatEvent:
+		return encodeBaseEvent(&e.BaseEvent, 2), nil
+	case *StateChangedEvent:
+		switch {
+		case e.Defeated:
+			return encodeBaseEvent(&e.BaseEvent, 4), nil
+		case e.Downed:
+			return encodeBaseEvent(&e.BaseEvent, 5), nil
+		default:
+			return encodeBaseEvent(&e.BaseEvent, 3), nil
+		}
+	case *TrackingChangedEvent:
+		if e.Tracking {
+			return encodeBaseEvent(&e.BaseEvent, 6), nil
+		}
+		return encodeBaseEvent(&e.BaseEvent, 7), nil
+	case *HealthUpdateEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 8)
+		event.DstAgent = uint64(e.Percentage)
+		return event, nil
+	case *LogStartEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 9)
+		event.SrcAgent = arcdpsID
+		event.Value = int32(uint32(e.ServerTime.Unix()))
+		event.BuffDmg = int32(uint32(e.LocalTime.Unix()))
+		return event, nil
+	case *LogEndEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 10)
+		event.SrcAgent = arcdpsID
+		event.Value = int32(uint32(e.RealServerTime.Unix()))
+		event.BuffDmg = int32(uint32(e.RealLocalTime.Unix()))
+		return event, nil
+	case *WeaponSwapEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 11)
+		event.DstAgent = uint64(e.WeaponSet)
+		return event, nil
+	case *MaxHealthUpdateEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 12)
+		event.DstAgent = e.MaxHealth
+		return event, nil
+	case *RewardEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 17)
+		event.DstAgent = uint64(e.RewardID)
+		event.Value = int32(e.RewardType)
+		return event, nil
+	case *InitialBuffEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 18)
+		event.DstAgent = agentAddr(e.Target)
+		event.DstInstID = agentInstID(e.Target)
+		event.DstMasterInstID = agentMasterInstID(e.Target)
+		event.SkillID = uint32(e.SkillID)
+		event.Buff = 18
+		event.Value = millis(e.Duration)
+		event.Pad61_64 = e.Instance
+		event.IsShields = boolByte(e.Active)
+		return event, nil
+	case *PositionEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 19)
+		event.DstAgent, event.Value = encodeVector(e.X, e.Y, e.Z)
+		return event, nil
+	case *VelocityEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 20)
+		event.DstAgent, event.Value = encodeVector(e.X, e.Y, e.Z)
+		return event, nil
+	case *FacingEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 21)
+		event.DstAgent, _ = encodeVector(e.X, e.Y, 0)
+		return event, nil
+	case *TeamChangeEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 22)
+		event.DstAgent = uint64(e.TeamID)
+		return event, nil
+	case *WeakPointEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 23)
+		event.DstAgent = agentAddr(e.Boss)
+		event.Value = int32(boolByte(e.Targetable))
+		return event, nil
+	case *TargetableEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 24)
+		event.DstAgent = uint64(boolByte(e.Targetable))
+		return event, nil
+	case *BreakbarStateEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 34)
+		event.Value = int32(e.State)
+		return event, nil
+	case *BreakbarPercentEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 35)
+		event.Value = int32(math.Float32bits(e.Percent))
+		return event, nil
+	case *TagEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 37)
+		event.Value = int32(e.MarkerID)
+		event.Buff = boolByte(e.IsCommander)
+		return event, nil
+	case *BarrierUpdateEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 38)
+		event.DstAgent = uint64(e.Percentage)
+		return event, nil
+	case *StatResetEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 39)
+		event.SrcAgent = uint64(e.Species)
+		return event, nil
+	case *BuffActiveEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 27)
+		event.DstAgent = uint64(e.Instance)
+		return event, nil
+	case *BuffResetEvent:
+		event := encodeBaseEvent(&e.BaseEvent, 28)
+		event.Value = millis(e.Duration)
+		event.Pad61_64 = e.Instance
+		return event, nil
+	case *GuildEvent:
+		guid := e.Guild
+		guid[0], guid[3] = guid[3], guid[0]
+		guid[1], guid[2] = guid[2], guid[1]
+		guid[4], guid[5] = guid[5], guid[4]
+		guid[6], guid[7] = guid[7], guid[6]
+
+		event := encodeBaseEvent(&e.BaseEvent, 29)
+		event.DstAgent = binary.LittleEndian.Uint64(guid[:])
+		event.Value = int32(binary.LittleEndian.Uint32(guid[8:]))
+		event.BuffDmg = int32(binary.LittleEndian.Uint32(guid[12:]))
+		return event, nil
+	case *SkillActivationEvent:
+		event := encodeCommonEvent(&e.CommonEvent)
+		event.Value = millis(e.ExpectedDuration)
+		if e.Quickness {
+			event.IsActivation = 2 // ACTV_QUICKNESS
+		} else {
+			event.IsActivation = 1 // ACTV_NORMAL
+		}
+		return event, nil
+	case *SkillActivatedEvent:
+		event := encodeCommonEvent(&e.CommonEvent)
+		event.Value = millis(e.Duration)
+		switch {
+		case e.Reset:
+			event.IsActivation = 5 // ACTV_RESET
+		case e.Complete:
+			event.IsActivation = 3 // ACTV_CANCEL_FIRE
+		default:
+			event.IsActivation = 4 // ACTV_CANCEL_CANCEL
+		}
+		return event, nil
+	case *BuffRemoveEvent:
+		event := encodeCommonEvent(&e.CommonEvent)
+
+		// undo the source/target swap done by parseBuffRemoveEvent
+		event.SrcAgent, event.DstAgent = event.DstAgent, event.SrcAgent
+		event.SrcInstID, event.DstInstID = event.DstInstID, event.SrcInstID
+		event.SrcMasterInstID, event.DstMasterInstID = event.DstMasterInstID, event.SrcMasterInstID
+
+		event.Buff = 1
+		event.Value = millis(e.Duration)
+		event.BuffDmg = millis(e.Intensity)
+		event.Result = uint8(e.Count)
+		event.Pad61_64 = e.Instance
+		switch {
+		case e.All:
+			event.IsBuffRemove = 1 // CBTB_ALL
+		case e.Synthesized:
+			event.IsBuffRemove = 3 // CBTB_MANUAL
+		default:
+			event.IsBuffRemove = 2 // CBTB_SINGLE
+		}
+		return event, nil
+	case *ApplyBuffEvent:
+		event := encodeCommonEvent(&e.CommonEvent)
+		event.Buff = 1
+		event.Value = millis(e.Duration)
+		event.Pad61_64 = e.Instance
+		event.IsShields = boolByte(e.Active)
+		if e.NewDuration != 0 {
+			event.IsOffCycle = 1
+			event.OverstackValue = uint32(millis(e.NewDuration))
+		} else {
+			event.OverstackValue = uint32(millis(e.WastedDuration))
+		}
+		return event, nil
+	case *BuffDamageEvent:
+		event := encodeCommonEvent(&e.CommonEvent)
+		event.Buff = 1
+		event.BuffDmg = int32(e.Damage)
+		event.IsOffCycle = boolByte(!e.Tick)
+		event.Result = e.invulnType
+		return event, nil
+	case *DirectDamageEvent:
+		event := encodeCommonEvent(&e.CommonEvent)
+		event.Value = int32(e.Damage)
+		event.OverstackValue = uint32(e.Barrier)
+		event.IsOffCycle = boolByte(e.WasDowned)
+		switch {
+		case e.BecameDowned:
+			event.Result = 9 // CBTR_DOWNED
+		case e.BecameDefeated:
+			event.Result = 8 // CBTR_KILLINGBLOW
+		case e.Missed:
+			event.Result = 7 // CBTR_BLIND
+		case e.Invulnerable:
+			event.Result = 6 // CBTR_ABSORB
+		case e.Interrupt:
+			event.Result = 5 // CBTR_INTERRUPT
+		case e.Evaded:
+			event.Result = 4 // CBTR_EVADE
+		case e.Blocked:
+			event.Result = 3 // CBTR_BLOCK
+		case e.Glancing:
+			event.Result = 2 // CBTR_GLANCE
+		case e.Critical:
+			event.Result = 1 // CBTR_CRIT
+		default:
+			event.Result = 0 // CBTR_NORMAL
+		}
+		return event, nil
+	case *BreakbarDamageEvent:
+		event := encodeCommonEvent(&e.CommonEvent)
+		event.Value = int32(math.Round(e.Damage * 10))
+		event.Result = 10 // CBTR_BREAKBAR
+		return event, nil
+	case *UnknownEvent:
+		return e.raw, nil
+	default:
+		return cbtevent1{}, errors.Errorf("evtc: cannot encode event of type %T", e)
+	}
+}
@@ -0,0 +1,355 @@
+package evtc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+const (
+	testPlayer1 = 0x100
+	testPlayer2 = 0x200
+	testBoss    = 0x300
+	testMinion  = 0x400
+	testGadget  = 0x500
+)
+
+func testAgents() []agent {
+	agents := []agent{
+		{Addr: testPlayer1, Prof: uint32(Guardian), IsElite: uint32(Firebrand), Toughness: 10, HitboxWidth: 48, HitboxHeight: 240},
+		{Addr: testPlayer2, Prof: uint32(Mesmer), IsElite: 1, Healing: 10, HitboxWidth: 48, HitboxHeight: 240},
+		{Addr: testBoss, Prof: 15438, IsElite: 0xffffffff, HitboxWidth: 400, HitboxHeight: 600},
+		{Addr: testMinion, Prof: 6519, IsElite: 0xffffffff, HitboxWidth: 50, HitboxHeight: 100},
+		{Addr: testGadget, Prof: 0xffff0123, IsElite: 0xffffffff},
+	}
+	copy(agents[0].Name[:], "First Player\x00:First.1234\x001\x00")
+	copy(agents[1].Name[:], "Second Player\x00:Second.5678\x002\x00")
+	copy(agents[2].Name[:], "Vale Guardian\x00\x00\x00")
+	copy(agents[3].Name[:], "Mirror Image\x00\x00\x00")
+	copy(agents[4].Name[:], "Gadget\x00\x00\x00")
+	return agents
+}
+
+func testSkills() []skill {
+	skills := []skill{{ID: 740}, {ID: 736}, {ID: 5491}, {ID: 9143}}
+	copy(skills[0].Name[:], "Might")
+	copy(skills[1].Name[:], "Bleeding")
+	copy(skills[2].Name[:], "Fire Attunement")
+	copy(skills[3].Name[:], "Sword of Justice")
+	return skills
+}
+
+// testUnknownEvents are records with enum values this package does not
+// recognize. They must be written back unchanged.
+var testUnknownEvents = []cbtevent1{
+	{Time: 1500, SrcAgent: testPlayer1, DstAgent: 0x1234, Value: -7, BuffDmg: 99, OverstackValue: 5, SkillID: 42, SrcInstID: 1, IsStateChange: 99, Pad61_64: 0xdeadbeef},
+	{Time: 1501, SrcAgent: testPlayer1, DstAgent: testBoss, Value: 100, SkillID: 5491, SrcInstID: 1, DstInstID: 3, IsActivation: 9},
+	{Time: 1502, SrcAgent: testBoss, DstAgent: testPlayer1, Value: 100, SkillID: 740, SrcInstID: 3, DstInstID: 1, Buff: 1, IsBuffRemove: 9},
+	{Time: 1503, SrcAgent: testPlayer1, DstAgent: testBoss, Value: 100, SkillID: 9143, SrcInstID: 1, DstInstID: 3, Result: 11},
+}
+
+func testEvents() []cbtevent1 {
+	f := func(v float32) int32 {
+		return int32(math.Float32bits(v))
+	}
+
+	events := []cbtevent1{
+		// metadata
+		{Time: 1000, SrcAgent: testPlayer1, IsStateChange: 13},
+		{Time: 1000, SrcAgent: 0, IsStateChange: 14},
+		{Time: 1000, SrcAgent: 100000, IsStateChange: 15},
+		{Time: 1000, SrcAgent: 1001, IsStateChange: 16},
+		{Time: 1000, SrcAgent: 1149, IsStateChange: 25},
+		{Time: 1000, SkillID: 740, SrcMasterInstID: 25, OverstackValue: 30000, IsOffCycle: uint8(BoonCategory), Pad61_64: 0x0104, IsStateChange: 30},
+		{Time: float32Bits(17, 4), SrcAgent: float32Bits(2, 30), DstAgent: float32Bits(0.5, 1), Value: f(0), BuffDmg: f(0), SkillID: 740, IsShields: 1, IsStateChange: 31},
+		{Time: float32Bits(20, 1200), SrcAgent: float32Bits(180, 0.75), SkillID: 5491, IsStateChange: 32},
+		{Time: 1000, SrcAgent: 1, DstAgent: 250, SkillID: 5491, IsStateChange: 33},
+		{Time: 1000, SrcAgent: arcdpsID, Value: 1600000000, BuffDmg: 1600000001, IsStateChange: 9},
+
+		// skill activations
+		{Time: 1010, SrcAgent: testPlayer1, DstAgent: testBoss, Value: 500, SkillID: 9143, SrcInstID: 1, DstInstID: 3, IsActivation: 1},
+		{Time: 1011, SrcAgent: testPlayer2, DstAgent: testBoss, Value: 400, SkillID: 9143, SrcInstID: 2, DstInstID: 3, IsActivation: 2},
+		{Time: 1012, SrcAgent: testPlayer1, DstAgent: testBoss, Value: 500, SkillID: 9143, SrcInstID: 1, DstInstID: 3, IsActivation: 3},
+		{Time: 1013, SrcAgent: testPlayer2, DstAgent: testBoss, Value: 200, SkillID: 9143, SrcInstID: 2, DstInstID: 3, IsActivation: 4},
+		{Time: 1014, SrcAgent: testBoss, DstAgent: testPlayer1, Value: 900, SkillID: 5491, SrcInstID: 3, DstInstID: 1, IsActivation: 5},
+		{Time: 1015, SrcAgent: testMinion, DstAgent: testBoss, Value: 900, SkillID: 9143, SrcInstID: 4, DstInstID: 3, SrcMasterInstID: 2, IsActivation: 1},
+		{Time: 1016, SrcAgent: testGadget, SrcInstID: 5, SkillID: 5491, IsActivation: 1},
+
+		// buffs
+		{Time: 1020, SrcAgent: testPlayer2, DstAgent: testPlayer1, Value: 5000, OverstackValue: 100, SkillID: 740, SrcInstID: 2, DstInstID: 1, Buff: 1, Pad61_64: 7, IsShields: 1},
+		{Time: 1021, SrcAgent: testPlayer2, DstAgent: testPlayer1, Value: 5000, OverstackValue: 6000, SkillID: 740, SrcInstID: 2, DstInstID: 1, Buff: 1, Pad61_64: 7, IsOffCycle: 1},
+		{Time: 1022, SrcAgent: testPlayer1, DstAgent: testPlayer2, Value: 4000, BuffDmg: 4000, Result: 1, SkillID: 740, SrcInstID: 1, DstInstID: 2, Buff: 1, Pad61_64: 7, IsBuffRemove: 2},
+		{Time: 1023, SrcAgent: testPlayer1, DstAgent: testPlayer2, Value: 4000, BuffDmg: 4000, Result: 1, SkillID: 740, SrcInstID: 1, DstInstID: 2, Buff: 1, Pad61_64: 7, IsBuffRemove: 3},
+		{Time: 1024, SrcAgent: testPlayer1, DstAgent: testPlayer2, Value: 8000, BuffDmg: 4000, Result: 2, SkillID: 740, SrcInstID: 1, DstInstID: 2, Buff: 1, IsBuffRemove: 1},
+		{Time: 1025, SrcAgent: testBoss, DstAgent: testPlayer1, BuffDmg: 150, SkillID: 736, SrcInstID: 3, DstInstID: 1, Buff: 1, Iff: 1},
+		{Time: 1026, SrcAgent: testBoss, DstAgent: testPlayer1, BuffDmg: 150, SkillID: 736, SrcInstID: 3, DstInstID: 1, Buff: 1, Iff: 1, IsOffCycle: 1, Result: 1},
+		{Time: 1027, SrcAgent: testPlayer1, DstAgent: testPlayer1, Value: 5000, SkillID: 740, SrcInstID: 1, DstInstID: 1, Buff: 18, Pad61_64: 9, IsShields: 1, IsStateChange: 18},
+		{Time: 1028, SrcAgent: testPlayer1, DstAgent: 9, SrcInstID: 1, IsStateChange: 27},
+		{Time: 1029, SrcAgent: testPlayer1, Value: 3000, SrcInstID: 1, Pad61_64: 9, IsStateChange: 28},
+	}
+
+	// direct damage, one record per result
+	for result := uint8(0); result <= 10; result++ {
+		// only direct damage records whether the target was downed
+		var downed uint8
+		if result < 10 {
+			downed = result & 2 >> 1
+		}
+		events = append(events, cbtevent1{
+			Time:       1030 + uint64(result),
+			SrcAgent:   testPlayer1,
+			DstAgent:   testBoss,
+			Value:      1000 + int32(result),
+			SkillID:    9143,
+			SrcInstID:  1,
+			DstInstID:  3,
+			Iff:        1,
+			Result:     result,
+			IsNinety:   result & 1,
+			IsFifty:    result & 2 >> 1,
+			IsMoving:   result & 4 >> 2,
+			IsFlanking: result & 1,
+			IsOffCycle: downed,
+		})
+	}
+	events = append(events,
+		cbtevent1{Time: 1045, SrcAgent: testMinion, DstAgent: testBoss, Value: 250, OverstackValue: 50, SkillID: 9143, SrcInstID: 4, DstInstID: 3, SrcMasterInstID: 2, Iff: 1},
+	)
+
+	// statechanges
+	events = append(events, []cbtevent1{
+		{Time: 1050, SrcAgent: testPlayer1, DstAgent: 1, SrcInstID: 1, IsStateChange: 1},
+		{Time: 1051, SrcAgent: testPlayer1, SrcInstID: 1, IsStateChange: 2},
+		{Time: 1052, SrcAgent: testPlayer1, SrcInstID: 1, IsStateChange: 3},
+		{Time: 1053, SrcAgent: testPlayer1, SrcInstID: 1, IsStateChange: 4},
+		{Time: 1054, SrcAgent: testPlayer1, SrcInstID: 1, IsStateChange: 5},
+		{Time: 1055, SrcAgent: testBoss, SrcInstID: 3, IsStateChange: 6},
+		{Time: 1056, SrcAgent: testBoss, SrcInstID: 3, IsStateChange: 7},
+		{Time: 1057, SrcAgent: testBoss, DstAgent: 9950, SrcInstID: 3, IsStateChange: 8},
+		{Time: 1058, SrcAgent: testPlayer1, DstAgent: 4, SrcInstID: 1, IsStateChange: 11},
+		{Time: 1059, SrcAgent: testBoss, DstAgent: 22021440, SrcInstID: 3, IsStateChange: 12},
+		{Time: 1060, SrcAgent: testPlayer1, DstAgent: 55, Value: 2, SrcInstID: 1, IsStateChange: 17},
+		{Time: 1061, SrcAgent: testPlayer1, DstAgent: float32Bits(1.5, -2.25), Value: f(100), SrcInstID: 1, IsStateChange: 19},
+		{Time: 1062, SrcAgent: testPlayer1, DstAgent: float32Bits(0.5, 0.25), Value: f(-1), SrcInstID: 1, IsStateChange: 20},
+		{Time: 1063, SrcAgent: testPlayer1, DstAgent: float32Bits(0, 1), SrcInstID: 1, IsStateChange: 21},
+		{Time: 1064, SrcAgent: testBoss, DstAgent: 9, SrcInstID: 3, IsStateChange: 22},
+		{Time: 1065, SrcAgent: testGadget, DstAgent: testBoss, Value: 1, SrcInstID: 5, IsStateChange: 23},
+		{Time: 1066, SrcAgent: testBoss, DstAgent: 1, SrcInstID: 3, IsStateChange: 24},
+		{Time: 1067, SrcAgent: testPlayer1, DstAgent: 0x0123456789abcdef, Value: 0x01020304, BuffDmg: 0x05060708, SrcInstID: 1, IsStateChange: 29},
+		{Time: 1068, SrcAgent: testBoss, Value: 0, SrcInstID: 3, IsStateChange: 34},
+		{Time: 1069, SrcAgent: testBoss, Value: f(0.5), SrcInstID: 3, IsStateChange: 35},
+		{Time: 1070, SrcAgent: testPlayer1, Value: 5, Buff: 1, SrcInstID: 1, IsStateChange: 37},
+		{Time: 1071, SrcAgent: testPlayer2, Value: 3, SrcInstID: 2, IsStateChange: 37},
+		{Time: 1072, SrcAgent: testPlayer1, DstAgent: 1250, SrcInstID: 1, IsStateChange: 38},
+		{Time: 1073, SrcAgent: 15438, IsStateChange: 39},
+	}...)
+
+	events = append(events, testUnknownEvents...)
+
+	return append(events, cbtevent1{Time: 2000, SrcAgent: arcdpsID, Value: 1600000001, BuffDmg: 1600000002, IsStateChange: 10})
+}
+
+func writeTestLog(t *testing.T, revision uint8) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	write := func(data interface{}) {
+		if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := header{Revision: revision, Boss: 15438}
+	copy(h.Magic[:], "EVTC")
+	copy(h.Date[:], "EVTC20200101")
+	write(h)
+
+	agents := testAgents()
+	write(uint32(len(agents)))
+	write(agents)
+
+	skills := testSkills()
+	write(uint32(len(skills)))
+	write(skills)
+
+	for _, e := range testEvents() {
+		if revision == 0 {
+			write(cbtevent0{
+				Time:            e.Time,
+				SrcAgent:        e.SrcAgent,
+				DstAgent:        e.DstAgent,
+				Value:           e.Value,
+				BuffDmg:         e.BuffDmg,
+				OverstackValue:  uint16(e.OverstackValue),
+				SkillID:         uint16(e.SkillID),
+				SrcInstID:       e.SrcInstID,
+				DstInstID:       e.DstInstID,
+				SrcMasterInstID: e.SrcMasterInstID,
+				Iff:             e.Iff,
+				Buff:            e.Buff,
+				Result:          e.Result,
+				IsActivation:    e.IsActivation,
+				IsBuffRemove:    e.IsBuffRemove,
+				IsNinety:        e.IsNinety,
+				IsFifty:         e.IsFifty,
+				IsMoving:        e.IsMoving,
+				IsStateChange:   e.IsStateChange,
+				IsFlanking:      e.IsFlanking,
+				IsShields:       e.IsShields,
+				IsOffCycle:      e.IsOffCycle,
+			})
+		} else {
+			write(e)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// allEventTypes lists every typed event that encodeEvent handles.
+var allEventTypes = []Event{
+	(*EnterCombatEvent)(nil),
+	(*ExitCombatEvent)(nil),
+	(*StateChangedEvent)(nil),
+	(*TrackingChangedEvent)(nil),
+	(*HealthUpdateEvent)(nil),
+	(*LogStartEvent)(nil),
+	(*LogEndEvent)(nil),
+	(*WeaponSwapEvent)(nil),
+	(*MaxHealthUpdateEvent)(nil),
+	(*RewardEvent)(nil),
+	(*InitialBuffEvent)(nil),
+	(*PositionEvent)(nil),
+	(*VelocityEvent)(nil),
+	(*FacingEvent)(nil),
+	(*TeamChangeEvent)(nil),
+	(*WeakPointEvent)(nil),
+	(*TargetableEvent)(nil),
+	(*BreakbarStateEvent)(nil),
+	(*BreakbarPercentEvent)(nil),
+	(*TagEvent)(nil),
+	(*BarrierUpdateEvent)(nil),
+	(*StatResetEvent)(nil),
+	(*BuffActiveEvent)(nil),
+	(*BuffResetEvent)(nil),
+	(*GuildEvent)(nil),
+	(*SkillActivationEvent)(nil),
+	(*SkillActivatedEvent)(nil),
+	(*BuffRemoveEvent)(nil),
+	(*ApplyBuffEvent)(nil),
+	(*BuffDamageEvent)(nil),
+	(*DirectDamageEvent)(nil),
+	(*BreakbarDamageEvent)(nil),
+	(*UnknownEvent)(nil),
+}
+
+func encodeTestChain(t *testing.T, chain *EventChain) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, chain); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, revision := range []uint8{0, 1} {
+		t.Run(fmt.Sprintf("revision %d", revision), func(t *testing.T) {
+			raw := writeTestLog(t, revision)
+			chain, err := Parse(bytes.NewReader(raw), UnknownEvents(SurfaceUnknownEvents))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			seen := make(map[string]bool)
+			for _, e := range chain.Events {
+				seen[fmt.Sprintf("%T", e)] = true
+			}
+			for _, e := range allEventTypes {
+				if typ := fmt.Sprintf("%T", e); !seen[typ] {
+					t.Errorf("test log has no %s", typ)
+				}
+			}
+			if chain.PointOfView == nil || chain.PointOfView.Address() != testPlayer1 {
+				t.Errorf("PointOfView = %v; want agent %#x", chain.PointOfView, testPlayer1)
+			}
+			if chain.Language.String() != "en" {
+				t.Errorf("Language = %v; want en", chain.Language)
+			}
+			if _, ok := chain.Buffs()[740]; !ok {
+				t.Error("buff 740 is missing from Buffs")
+			}
+			if _, ok := chain.SkillInfo(5491); !ok {
+				t.Error("skill 5491 is missing from SkillInfo")
+			}
+
+			first := encodeTestChain(t, chain)
+			if revision == 1 && !bytes.Equal(first, raw) {
+				t.Errorf("Encode(Parse(log)) differs from log: %d bytes, want %d bytes", len(first), len(raw))
+				diffRecords(t, first, raw)
+			}
+
+			for _, u := range testUnknownEvents {
+				var want bytes.Buffer
+				if revision == 0 {
+					// revision 0 has no DstMasterInstID or padding
+					u = convert0(cbtevent0{
+						Time: u.Time, SrcAgent: u.SrcAgent, DstAgent: u.DstAgent,
+						Value: u.Value, BuffDmg: u.BuffDmg,
+						OverstackValue: uint16(u.OverstackValue), SkillID: uint16(u.SkillID),
+						SrcInstID: u.SrcInstID, DstInstID: u.DstInstID, SrcMasterInstID: u.SrcMasterInstID,
+						Iff: u.Iff, Buff: u.Buff, Result: u.Result, IsActivation: u.IsActivation,
+						IsBuffRemove: u.IsBuffRemove, IsStateChange: u.IsStateChange,
+					})
+				}
+				if err := binary.Write(&want, binary.LittleEndian, u); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Contains(first, want.Bytes()) {
+					t.Errorf("unknown event %+v was not written back unchanged", u)
+				}
+			}
+
+			reparsed, err := Parse(bytes.NewReader(first), UnknownEvents(SurfaceUnknownEvents))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reparsed.Events) != len(chain.Events) {
+				t.Errorf("re-parsed log has %d events; want %d", len(reparsed.Events), len(chain.Events))
+			}
+			for i := 0; i < len(chain.Events) && i < len(reparsed.Events); i++ {
+				if got, want := fmt.Sprintf("%T", reparsed.Events[i]), fmt.Sprintf("%T", chain.Events[i]); got != want {
+					t.Errorf("re-parsed event %d is %s; want %s", i, got, want)
+				}
+			}
+
+			second := encodeTestChain(t, reparsed)
+			if !bytes.Equal(first, second) {
+				t.Errorf("re-encoding changed the log: %d bytes, then %d bytes", len(first), len(second))
+			}
+		})
+	}
+}
+
+// diffRecords reports the first event record that differs between two
+// revision 1 logs with the same header and tables.
+func diffRecords(t *testing.T, got, want []byte) {
+	t.Helper()
+
+	size := binary.Size(cbtevent1{})
+	start := len(want) - len(testEvents())*size
+	for off := start; off+size <= len(got) && off+size <= len(want); off += size {
+		if bytes.Equal(got[off:off+size], want[off:off+size]) {
+			continue
+		}
+
+		var g, w cbtevent1
+		binary.Read(bytes.NewReader(got[off:]), binary.LittleEndian, &g)
+		binary.Read(bytes.NewReader(want[off:]), binary.LittleEndian, &w)
+		t.Errorf("event %d =\n%+v\nwant\n%+v", (off-start)/size, g, w)
+		return
+	}
+}
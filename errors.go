@@ -0,0 +1,113 @@
+package evtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBadMagic is returned when the input is not an EVTC file.
+var ErrBadMagic = errors.New("evtc: invalid magic number (expecting \"EVTC\")")
+
+type badMagicError struct {
+	magic [4]byte
+}
+
+func (e *badMagicError) Error() string {
+	return fmt.Sprintf("%v: %q", ErrBadMagic, e.magic[:])
+}
+
+func (e *badMagicError) Is(target error) bool {
+	return target == ErrBadMagic
+}
+
+// TruncatedError is returned when the input ends in the middle of a record.
+type TruncatedError struct {
+	// Offset is the byte offset of the start of the incomplete record.
+	Offset int64
+	// Section is the part of the file that was being read, such as
+	// "agents" or "events".
+	Section string
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("evtc: could not read %s at offset %d: log is truncated", e.Section, e.Offset)
+}
+
+// Unwrap returns io.ErrUnexpectedEOF.
+func (e *TruncatedError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// UnsupportedRevisionError is returned when the header declares a revision
+// of the event format that this package cannot read.
+type UnsupportedRevisionError struct {
+	Revision uint8
+}
+
+func (e *UnsupportedRevisionError) Error() string {
+	return fmt.Sprintf("evtc: unsupported revision %d", e.Revision)
+}
+
+// UnknownLanguageError is returned when the log's language statechange
+// has an ID that this package does not recognize.
+type UnknownLanguageError struct {
+	ID uint64
+}
+
+func (e *UnknownLanguageError) Error() string {
+	return fmt.Sprintf("evtc: unknown language ID %d", e.ID)
+}
+
+// UnknownEventError is returned for an unrecognized event when the decoder
+// was created with RejectUnknownEvents.
+type UnknownEventError struct {
+	Event *UnknownEvent
+	// Offset is the byte offset of the start of the event.
+	Offset int64
+}
+
+func (e *UnknownEventError) Error() string {
+	u := e.Event
+	return fmt.Sprintf("evtc: unrecognized event at offset %d (statechange %d, activation %d, buffremove %d, result %d)", e.Offset, u.StateChange, u.Activation, u.BuffRemove, u.Result)
+}
+
+// offsetReader counts the bytes read from the underlying reader.
+type offsetReader struct {
+	r      io.Reader
+	offset int64
+}
+
+func (r *offsetReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+// readSection reads a fixed-size record from r. If the input ends before the
+// record does, it returns a *TruncatedError. If the input ends exactly where
+// the record would start, it returns io.EOF.
+func readSection(r *offsetReader, section string, data interface{}) error {
+	offset := r.offset
+	switch err := binary.Read(r, binary.LittleEndian, data); err {
+	case nil:
+		return nil
+	case io.EOF:
+		return io.EOF
+	case io.ErrUnexpectedEOF:
+		return &TruncatedError{Offset: offset, Section: section}
+	default:
+		return errors.Wrapf(err, "evtc: could not read %s", section)
+	}
+}
+
+// readRequiredSection is readSection for records that must be present.
+func readRequiredSection(r *offsetReader, section string, data interface{}) error {
+	err := readSection(r, section, data)
+	if err == io.EOF {
+		return &TruncatedError{Offset: r.offset, Section: section}
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package evtc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestHeaderErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		badMagic  bool
+		truncated bool
+	}{
+		{name: "empty", input: "", badMagic: true},
+		{name: "too short for the magic number", input: "EV", badMagic: true},
+		{name: "zip archive", input: "PK\x03\x04garbage", badMagic: true},
+		{name: "other file", input: "not an arcdps log at all", badMagic: true},
+		{name: "truncated header", input: "EVTC20240101", truncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(bytes.NewReader([]byte(tt.input)))
+			if err == nil {
+				t.Fatal("Parse succeeded")
+			}
+
+			if got := errors.Is(err, ErrBadMagic); got != tt.badMagic {
+				t.Errorf("errors.Is(%q, ErrBadMagic) = %v", err, got)
+			}
+
+			var te *TruncatedError
+			if got := errors.As(err, &te); got != tt.truncated {
+				t.Errorf("errors.As(%q, *TruncatedError) = %v", err, got)
+			} else if got && (te.Offset != 0 || te.Section != "header") {
+				t.Errorf("TruncatedError = %+v; want offset 0 in header", te)
+			}
+		})
+	}
+}
+
+func TestUnknownEventError(t *testing.T) {
+	var buf bytes.Buffer
+	write := func(data interface{}) {
+		if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := header{Revision: 1}
+	copy(h.Magic[:], "EVTC")
+	write(h)
+	write(uint32(0))                                         // agents
+	write(uint32(0))                                         // skills
+	write(cbtevent1{Time: 1000, IsStateChange: 9, Value: 1}) // CBTS_LOGSTART
+	offset := int64(buf.Len())
+	write(cbtevent1{Time: 1000, IsStateChange: 250, Value: 1234}) // unassigned statechange
+
+	_, err := Parse(bytes.NewReader(buf.Bytes()), UnknownEvents(RejectUnknownEvents))
+
+	var ue *UnknownEventError
+	if !errors.As(err, &ue) {
+		t.Fatalf("errors.As(%v, *UnknownEventError) = false", err)
+	}
+	if ue.Offset != offset {
+		t.Errorf("Offset = %d; want %d", ue.Offset, offset)
+	}
+	if ue.Event.StateChange != 250 || ue.Event.Value != 1234 {
+		t.Errorf("Event = %+v", ue.Event)
+	}
+}
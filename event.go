@@ -3,11 +3,10 @@ package evtc
 import (
 	"encoding/binary"
 	"math"
+	"strconv"
 	"time"
 
-	"github.com/davecgh/go-spew/spew"
 	"github.com/google/uuid"
-	"github.com/pkg/errors"
 	"golang.org/x/text/language"
 )
 
@@ -28,6 +27,9 @@ type BaseEvent struct {
 	LocalTime  time.Time
 	ServerTime time.Time
 	Source     *Agent
+
+	// Tick is the raw arcdps timestamp of this event, in milliseconds.
+	Tick uint64
 }
 
 // Time implements Event.
@@ -40,6 +42,25 @@ func (e *BaseEvent) SourceAgent() *Agent {
 	return e.Source
 }
 
+// Since returns the time elapsed between start and this event, according to
+// the local clock. Pass EventChain.Start to get encounter-relative times.
+func (e *BaseEvent) Since(start time.Time) time.Duration {
+	return e.LocalTime.Sub(start)
+}
+
+func (e *BaseEvent) base() *BaseEvent {
+	return e
+}
+
+// baseEventOf returns the BaseEvent embedded in one of the event types
+// defined by this package.
+func baseEventOf(e Event) (*BaseEvent, bool) {
+	if b, ok := e.(interface{ base() *BaseEvent }); ok {
+		return b.base(), true
+	}
+	return nil, false
+}
+
 // SkillEvent is the base interface for events related to skills.
 type SkillEvent interface {
 	Event
@@ -109,13 +130,12 @@ func (e *CommonEvent) IsMoving() bool { return e.Moving }
 func (e *CommonEvent) IsFlanking() bool { return e.Flanking }
 
 func makeBaseEvent(typ string, chain *EventChain, event cbtevent1) BaseEvent {
-	offset := time.Duration(event.Time)*time.Millisecond + chain.timeOffset
-
 	return BaseEvent{
 		Type:       typ,
-		LocalTime:  chain.localTime.Add(offset),
-		ServerTime: chain.localTime.Add(offset),
+		LocalTime:  chain.localTimeAt(event.Time),
+		ServerTime: chain.serverTimeAt(event.Time),
 		Source:     chain.agents[event.SrcAgent],
+		Tick:       event.Time,
 	}
 }
 
@@ -264,6 +284,7 @@ type DirectDamageEvent struct {
 }
 type InitialBuffEvent struct {
 	BaseEvent
+	Target    *Agent
 	SkillID   int
 	SkillName string
 	Duration  time.Duration
@@ -275,6 +296,10 @@ func (e *InitialBuffEvent) Skill() (id int, name string) {
 	return e.SkillID, e.SkillName
 }
 
+func (e *InitialBuffEvent) TargetAgent() *Agent {
+	return e.Target
+}
+
 type WeaponSwapEvent struct {
 	BaseEvent
 	WeaponSet int
@@ -298,6 +323,117 @@ type TargetableEvent struct {
 	Targetable bool
 }
 
+// BreakbarState is the state of an agent's defiance bar.
+type BreakbarState uint16
+
+const (
+	BreakbarActive BreakbarState = iota
+	BreakbarRecover
+	BreakbarImmune
+	BreakbarNone
+)
+
+func (s BreakbarState) String() string {
+	switch s {
+	case BreakbarActive:
+		return "Active"
+	case BreakbarRecover:
+		return "Recover"
+	case BreakbarImmune:
+		return "Immune"
+	case BreakbarNone:
+		return "None"
+	default:
+		return strconv.Itoa(int(s))
+	}
+}
+
+type BreakbarStateEvent struct {
+	BaseEvent
+	State BreakbarState
+}
+type BreakbarPercentEvent struct {
+	BaseEvent
+	// Percent is the fraction of the defiance bar that remains, where 1
+	// is a full bar.
+	Percent float32
+}
+type BreakbarDamageEvent struct {
+	CommonEvent
+	Damage float64
+}
+type BarrierUpdateEvent struct {
+	BaseEvent
+	// Percentage is the barrier as a percentage of maximum health,
+	// fixed-point with two decimal places.
+	// (12.5% is represented as 1250)
+	Percentage uint16
+}
+type TagEvent struct {
+	BaseEvent
+	// MarkerID identifies the marker above the agent, or is 0 if the
+	// marker was removed. The IDs depend on the game build.
+	MarkerID int
+	// IsCommander is set if the marker is a commander tag rather than a
+	// squad marker. Older versions of arcdps never set it.
+	IsCommander bool
+}
+type StatResetEvent struct {
+	BaseEvent
+	// Species is the species ID of the agent that triggered the reset.
+	Species int
+}
+
+// UnknownEvent is a combat event with an enum value this package does not
+// recognize. It carries the raw fields of the event.
+type UnknownEvent struct {
+	BaseEvent
+	Target *Agent
+
+	StateChange uint8
+	Activation  uint8
+	BuffRemove  uint8
+	Result      uint8
+	Buff        uint8
+	SrcAgent    uint64
+	DstAgent    uint64
+	Value       int32
+	BuffDmg     int32
+	Overstack   uint32
+	SkillID     uint32
+	Pad         uint32
+
+	raw cbtevent1
+}
+
+// TargetAgent returns the agent the raw event's destination refers to, if
+// any.
+func (e *UnknownEvent) TargetAgent() *Agent {
+	return e.Target
+}
+
+func makeUnknownEvent(chain *EventChain, event cbtevent1) *UnknownEvent {
+	return &UnknownEvent{
+		BaseEvent: makeBaseEvent("Unknown", chain, event),
+		Target:    chain.agents[event.DstAgent],
+
+		StateChange: event.IsStateChange,
+		Activation:  event.IsActivation,
+		BuffRemove:  event.IsBuffRemove,
+		Result:      event.Result,
+		Buff:        event.Buff,
+		SrcAgent:    event.SrcAgent,
+		DstAgent:    event.DstAgent,
+		Value:       event.Value,
+		BuffDmg:     event.BuffDmg,
+		Overstack:   event.OverstackValue,
+		SkillID:     event.SkillID,
+		Pad:         event.Pad61_64,
+
+		raw: event,
+	}
+}
+
 func parseStateChangeEvent(chain *EventChain, event cbtevent1) (Event, error) {
 	switch event.IsStateChange {
 	case 1: // CBTS_ENTERCOMBAT, src_agent entered combat, dst_agent is subgroup
@@ -352,6 +488,7 @@ func parseStateChangeEvent(chain *EventChain, event cbtevent1) (Event, error) {
 				Type:       "LogStart",
 				ServerTime: chain.serverTime,
 				LocalTime:  chain.localTime,
+				Tick:       event.Time,
 			},
 		}, nil
 	case 10: // CBTS_LOGEND, log end. value = server unix timestamp **uint32**. buff_dmg = local unix timestamp. src_agent = 0x637261 (arcdps id)
@@ -390,7 +527,7 @@ func parseStateChangeEvent(chain *EventChain, event cbtevent1) (Event, error) {
 		case 5:
 			chain.Language = language.Chinese
 		default:
-			return nil, errors.Errorf("evtc: unknown language ID %d", event.SrcAgent)
+			return nil, &UnknownLanguageError{ID: event.SrcAgent}
 		}
 		return nil, nil
 	case 15: // CBTS_GWBUILD, src_agent is game build
@@ -414,6 +551,7 @@ func parseStateChangeEvent(chain *EventChain, event cbtevent1) (Event, error) {
 		abe := e.(*ApplyBuffEvent)
 		return &InitialBuffEvent{
 			BaseEvent: abe.BaseEvent,
+			Target:    abe.Target,
 			SkillID:   abe.SkillID,
 			SkillName: abe.SkillName,
 			Duration:  abe.Duration,
@@ -485,10 +623,48 @@ func parseStateChangeEvent(chain *EventChain, event cbtevent1) (Event, error) {
 			BaseEvent: makeBaseEvent("Guild", chain, event),
 			Guild:     guid,
 		}, nil
+	case 30: // CBTS_BUFFINFO, buff definition
+		parseBuffInfo(chain, event)
+		return nil, nil
+	case 31: // CBTS_BUFFFORMULA, one attribute modifier of a buff definition
+		parseBuffFormula(chain, event)
+		return nil, nil
+	case 32: // CBTS_SKILLINFO, skill definition
+		parseSkillInfo(chain, event)
+		return nil, nil
+	case 33: // CBTS_SKILLTIMING, one action timing of a skill definition
+		parseSkillTiming(chain, event)
+		return nil, nil
+	case 34: // CBTS_BREAKBARSTATE, src_agent is agent, value is u16 breakbar state
+		return &BreakbarStateEvent{
+			BaseEvent: makeBaseEvent("BreakbarState", chain, event),
+			State:     BreakbarState(uint16(event.Value)),
+		}, nil
+	case 35: // CBTS_BREAKBARPERCENT, src_agent is agent, value is float percent
+		return &BreakbarPercentEvent{
+			BaseEvent: makeBaseEvent("BreakbarPercent", chain, event),
+			Percent:   math.Float32frombits(uint32(event.Value)),
+		}, nil
+	case 37: // CBTS_TAG, src_agent is agent, value is id of marker (volatile, depends on game build), buff is 1 if commander tag
+		return &TagEvent{
+			BaseEvent:   makeBaseEvent("Tag", chain, event),
+			MarkerID:    int(event.Value),
+			IsCommander: event.Buff != 0,
+		}, nil
+	case 38: // CBTS_BARRIERUPDATE, src_agent has had barrier changed. dst_agent = percent * 10000 (eg. 99.5% will be 9950)
+		return &BarrierUpdateEvent{
+			BaseEvent:  makeBaseEvent("BarrierUpdate", chain, event),
+			Percentage: uint16(event.DstAgent),
+		}, nil
+	case 39: // CBTS_STATRESET, src_agent is species id of agent that triggered the reset
+		be := makeBaseEvent("StatReset", chain, event)
+		be.Source = nil // src_agent is not an agent
+		return &StatResetEvent{
+			BaseEvent: be,
+			Species:   int(event.SrcAgent),
+		}, nil
 	default:
-		// TODO: generic format for unhandled cbtstatechange events
-		spew.Dump(event)
-		panic("TODO")
+		return makeUnknownEvent(chain, event), nil
 	}
 }
 
@@ -528,8 +704,7 @@ func parseActivationEvent(chain *EventChain, event cbtevent1) (Event, error) {
 			Reset:       true,
 		}, nil
 	default:
-		spew.Dump(event)
-		panic("TODO")
+		return makeUnknownEvent(chain, event), nil
 	}
 }
 
@@ -556,8 +731,7 @@ func parseBuffRemoveEvent(chain *EventChain, event cbtevent1) (Event, error) {
 		e.Synthesized = true
 		e.All = false
 	default:
-		spew.Dump(event)
-		panic("TODO")
+		return makeUnknownEvent(chain, event), nil
 	}
 
 	return e, nil
@@ -593,6 +767,13 @@ func parseBuffDamageEvent(chain *EventChain, event cbtevent1) (Event, error) {
 }
 
 func parseDirectDamageEvent(chain *EventChain, event cbtevent1) (Event, error) {
+	if event.Result == 10 { // CBTR_BREAKBAR, value is damage to breakbar * 10
+		return &BreakbarDamageEvent{
+			CommonEvent: makeCommonEvent("BreakbarDamage", chain, event),
+			Damage:      float64(event.Value) / 10,
+		}, nil
+	}
+
 	e := &DirectDamageEvent{
 		CommonEvent: makeCommonEvent("DirectDamage", chain, event),
 		Damage:      int(event.Value),
@@ -625,8 +806,7 @@ func parseDirectDamageEvent(chain *EventChain, event cbtevent1) (Event, error) {
 	case 9: // CBTR_DOWNED, hit was downing hit
 		e.BecameDowned = true
 	default:
-		spew.Dump(event)
-		panic("TODO")
+		return makeUnknownEvent(chain, event), nil
 	}
 
 	return e, nil
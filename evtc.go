@@ -2,45 +2,32 @@
 package evtc
 
 import (
-	"encoding/binary"
 	"io"
-
-	"github.com/pkg/errors"
 )
 
 // Parse parses and EVTC file.
-func Parse(r io.Reader) (*EventChain, error) {
-	h, agents, skills, err := parseHeader(r)
+//
+// Parse keeps every event in memory. Use a Decoder to process large logs one
+// event at a time.
+func Parse(r io.Reader, opts ...Option) (*EventChain, error) {
+	d, err := NewDecoder(r, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	var events []cbtevent1
+	return d.decodeAll()
+}
 
-	switch h.Revision {
-	case 0:
-		for {
-			var event cbtevent0
-			if err := binary.Read(r, binary.LittleEndian, &event); err == io.EOF {
-				break
-			} else if err != nil {
-				return nil, errors.Wrap(err, "evtc: error reading events")
-			}
-			events = append(events, convert0(event))
-		}
-	case 1:
-		for {
-			var event cbtevent1
-			if err := binary.Read(r, binary.LittleEndian, &event); err == io.EOF {
-				break
-			} else if err != nil {
-				return nil, errors.Wrap(err, "evtc: error reading events")
-			}
-			events = append(events, event)
+// decodeAll reads every remaining event into the chain's Events.
+func (d *Decoder) decodeAll() (*EventChain, error) {
+	chain := d.Header()
+	for {
+		e, err := d.Next()
+		if err == io.EOF {
+			return chain, nil
+		} else if err != nil {
+			return nil, err
 		}
+		chain.Events = append(chain.Events, e)
 	}
-
-	wrappedAgents := wrapAgents(agents, events)
-
-	return makeEventChain(h, wrappedAgents, skills, events)
 }
@@ -0,0 +1,215 @@
+package evtc
+
+import (
+	"time"
+)
+
+// Predicate selects events for EventChain.Filter.
+type Predicate struct {
+	match func(Event) bool
+
+	// candidates returns the positions in Events of every event that
+	// could match, or nil if this predicate cannot use an index.
+	candidates func(*eventIndex) []int
+}
+
+// BySource matches events caused by a.
+func BySource(a *Agent) Predicate {
+	return Predicate{
+		match: func(e Event) bool {
+			return e.SourceAgent() == a
+		},
+		candidates: func(idx *eventIndex) []int {
+			return nonNil(idx.source[a])
+		},
+	}
+}
+
+// ByTarget matches events that directly affect a.
+func ByTarget(a *Agent) Predicate {
+	return Predicate{
+		match: func(e Event) bool {
+			return targetOf(e) == a
+		},
+		candidates: func(idx *eventIndex) []int {
+			return nonNil(idx.target[a])
+		},
+	}
+}
+
+// BySkill matches skill events with the given skill ID.
+func BySkill(id int) Predicate {
+	return Predicate{
+		match: func(e Event) bool {
+			se, ok := e.(SkillEvent)
+			if !ok {
+				return false
+			}
+			skill, _ := se.Skill()
+			return skill == id
+		},
+		candidates: func(idx *eventIndex) []int {
+			return nonNil(idx.skill[id])
+		},
+	}
+}
+
+// Between matches events whose local time is at or after start and before
+// end.
+func Between(start, end time.Time) Predicate {
+	return Predicate{
+		match: func(e Event) bool {
+			t, _ := e.Time()
+			return !t.Before(start) && t.Before(end)
+		},
+	}
+}
+
+// OfType matches events of type T, for example OfType[*DirectDamageEvent]().
+// If T is an interface, such as CombatEvent, every event that implements it
+// matches.
+func OfType[T Event]() Predicate {
+	return Predicate{
+		match: func(e Event) bool {
+			_, ok := e.(T)
+			return ok
+		},
+	}
+}
+
+// EventIterator returns the events selected by EventChain.Filter.
+type EventIterator struct {
+	events     []Event
+	candidates []int
+	preds      []Predicate
+	pos        int
+}
+
+// Next returns the next matching event. The second return value is false
+// when there are no more events.
+func (it *EventIterator) Next() (Event, bool) {
+	for {
+		var e Event
+		if it.candidates != nil {
+			if it.pos >= len(it.candidates) {
+				return nil, false
+			}
+			e = it.events[it.candidates[it.pos]]
+		} else {
+			if it.pos >= len(it.events) {
+				return nil, false
+			}
+			e = it.events[it.pos]
+		}
+		it.pos++
+
+		if matchAll(e, it.preds) {
+			return e, true
+		}
+	}
+}
+
+// All returns the remaining matching events.
+func (it *EventIterator) All() []Event {
+	var events []Event
+	for e, ok := it.Next(); ok; e, ok = it.Next() {
+		events = append(events, e)
+	}
+	return events
+}
+
+// Filter returns an iterator over the events in Events that match every
+// predicate, in order. Source, target, and skill predicates are answered
+// from indices that are built the first time they are needed and rebuilt
+// if Events changes length. Call Reindex after replacing or modifying events
+// without changing the length of Events.
+func (c *EventChain) Filter(preds ...Predicate) *EventIterator {
+	it := &EventIterator{
+		events: c.Events,
+		preds:  preds,
+	}
+
+	var idx *eventIndex
+	for _, p := range preds {
+		if p.candidates == nil {
+			continue
+		}
+		if idx == nil {
+			idx = c.eventIndex()
+		}
+		if cand := p.candidates(idx); it.candidates == nil || len(cand) < len(it.candidates) {
+			it.candidates = cand
+		}
+	}
+
+	return it
+}
+
+func matchAll(e Event, preds []Predicate) bool {
+	for _, p := range preds {
+		if !p.match(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// Reindex discards the indices used by Filter, so they are rebuilt from the
+// current contents of Events the next time they are needed.
+func (c *EventChain) Reindex() {
+	c.indexLock.Lock()
+	c.index = nil
+	c.indexLock.Unlock()
+}
+
+type eventIndex struct {
+	count  int
+	source map[*Agent][]int
+	target map[*Agent][]int
+	skill  map[int][]int
+}
+
+func (c *EventChain) eventIndex() *eventIndex {
+	c.indexLock.Lock()
+	defer c.indexLock.Unlock()
+
+	if c.index != nil && c.index.count == len(c.Events) {
+		return c.index
+	}
+
+	idx := &eventIndex{
+		count:  len(c.Events),
+		source: make(map[*Agent][]int),
+		target: make(map[*Agent][]int),
+		skill:  make(map[int][]int),
+	}
+	for i, e := range c.Events {
+		src := e.SourceAgent()
+		idx.source[src] = append(idx.source[src], i)
+		dst := targetOf(e)
+		idx.target[dst] = append(idx.target[dst], i)
+		if se, ok := e.(SkillEvent); ok {
+			id, _ := se.Skill()
+			idx.skill[id] = append(idx.skill[id], i)
+		}
+	}
+
+	c.index = idx
+	return idx
+}
+
+// targetOf returns the agent directly affected by e, or nil.
+func targetOf(e Event) *Agent {
+	if te, ok := e.(interface{ TargetAgent() *Agent }); ok {
+		return te.TargetAgent()
+	}
+	return nil
+}
+
+// nonNil distinguishes "no matching events" from "no index".
+func nonNil(positions []int) []int {
+	if positions == nil {
+		return []int{}
+	}
+	return positions
+}
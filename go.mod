@@ -1,9 +1,8 @@
 module github.com/BenLubar/evtc
 
-go 1.12
+go 1.18
 
 require (
-	github.com/davecgh/go-spew v1.1.1
 	github.com/google/uuid v1.1.1
 	github.com/pkg/errors v0.9.1
 	golang.org/x/text v0.3.2
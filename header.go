@@ -1,6 +1,7 @@
 package evtc
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io"
 	"strings"
@@ -11,36 +12,58 @@ import (
 type header struct {
 	Magic    [4]byte // {'E', 'V', 'T', 'C'}
 	Date     [8]byte // arcdps build datestamp
-	Revision uint8   // 0 or 1; decides which cbtevent struct is used
+	Revision uint8   // decides the layout of the rest of the file; see revisions
 	Boss     uint16  // species ID
 	Reserved uint8   // unused; reserved
 }
 
-func parseHeader(r io.Reader) (header, []agent, map[uint32]string, error) {
-	var h header
-	if err := errors.Wrap(binary.Read(r, binary.LittleEndian, &h), "evtc: could not read header"); err != nil {
-		return header{}, nil, nil, err
+func parseHeader(r *offsetReader) (header, revision, []agent, []skill, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return header{}, nil, nil, nil, err
 	}
-	if h.Magic[0] != 'E' || h.Magic[1] != 'V' || h.Magic[2] != 'T' || h.Magic[3] != 'C' {
-		return header{}, nil, nil, errors.Errorf("evtc: invalid magic number (expecting \"EVTC\"): %q", h.Magic[:])
+	rev, ok := revisions[h.Revision]
+	if !ok {
+		return header{}, nil, nil, nil, &UnsupportedRevisionError{Revision: h.Revision}
 	}
-	var count uint32
-	if err := errors.Wrap(binary.Read(r, binary.LittleEndian, &count), "evtc: could not read agent count"); err != nil {
-		return header{}, nil, nil, err
+	agents, err := rev.readAgents(r)
+	if err != nil {
+		return header{}, nil, nil, nil, err
 	}
-	agents := make([]agent, count)
-	if err := errors.Wrap(binary.Read(r, binary.LittleEndian, agents), "evtc: could not read agents"); err != nil {
-		return header{}, nil, nil, err
+	skills, err := rev.readSkills(r)
+	if err != nil {
+		return header{}, nil, nil, nil, err
 	}
-	if err := errors.Wrap(binary.Read(r, binary.LittleEndian, &count), "evtc: could not read skill count"); err != nil {
-		return header{}, nil, nil, err
+
+	return h, rev, agents, skills, nil
+}
+
+// readHeader reads the header, checking the magic number before anything
+// else so that input that is too short to be a log, or that is some other
+// kind of file, is reported as ErrBadMagic rather than as truncated.
+func readHeader(r *offsetReader) (header, error) {
+	offset := r.offset
+
+	var buf [16]byte
+	n, err := io.ReadFull(r, buf[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return header{}, errors.Wrap(err, "evtc: could not read header")
 	}
-	skills := make([]skill, count)
-	if err := errors.Wrap(binary.Read(r, binary.LittleEndian, skills), "evtc: could not read skills"); err != nil {
-		return header{}, nil, nil, err
+
+	if n < 4 || string(buf[:4]) != "EVTC" {
+		var magic [4]byte
+		copy(magic[:], buf[:n])
+		return header{}, &badMagicError{magic}
+	}
+	if n < len(buf) {
+		return header{}, &TruncatedError{Offset: offset, Section: "header"}
 	}
 
-	return h, agents, wrapSkills(skills), nil
+	var h header
+	if err := binary.Read(bytes.NewReader(buf[:]), binary.LittleEndian, &h); err != nil {
+		return header{}, errors.Wrap(err, "evtc: could not read header")
+	}
+	return h, nil
 }
 
 type skill struct {
@@ -0,0 +1,241 @@
+// Package evtctest builds small EVTC logs in memory for tests.
+package evtctest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/BenLubar/evtc"
+)
+
+// Record is a combat event in the revision 1 layout.
+type Record struct {
+	Time            uint64
+	SrcAgent        uint64
+	DstAgent        uint64
+	Value           int32
+	BuffDmg         int32
+	OverstackValue  uint32
+	SkillID         uint32
+	SrcInstID       uint16
+	DstInstID       uint16
+	SrcMasterInstID uint16
+	DstMasterInstID uint16
+	Iff             uint8
+	Buff            uint8
+	Result          uint8
+	IsActivation    uint8
+	IsBuffRemove    uint8
+	IsNinety        uint8
+	IsFifty         uint8
+	IsMoving        uint8
+	IsStateChange   uint8
+	IsFlanking      uint8
+	IsShields       uint8
+	IsOffCycle      uint8
+	Pad61_64        uint32
+}
+
+type header struct {
+	Magic    [4]byte
+	Date     [8]byte
+	Revision uint8
+	Boss     uint16
+	Reserved uint8
+}
+
+type agent struct {
+	Addr          uint64
+	Prof          uint32
+	IsElite       uint32
+	Toughness     uint16
+	Concentration uint16
+	Healing       uint16
+	HitboxWidth   uint16
+	Condition     uint16
+	HitboxHeight  uint16
+	Name          [64]byte
+	Padding       [4]byte
+}
+
+type skill struct {
+	ID   uint32
+	Name [64]byte
+}
+
+// Log is an EVTC log under construction. Agents are given instance IDs in
+// the order they are added, and records are filled in with the instance IDs
+// of their agents and the masters of minions.
+//
+// Like arcdps, the parser only learns the instance ID of an agent from a
+// combat event the agent caused, so a master must cause a combat event
+// before its minions do for the minions to be linked to it.
+type Log struct {
+	// Boss is the species ID in the header.
+	Boss uint16
+
+	agents   []agent
+	skills   []skill
+	records  []Record
+	instance map[uint64]uint16
+	master   map[uint64]uint64
+}
+
+func (l *Log) addAgent(a agent, name string) {
+	copy(a.Name[:len(a.Name)-1], name)
+	l.agents = append(l.agents, a)
+
+	if l.instance == nil {
+		l.instance = make(map[uint64]uint16)
+		l.master = make(map[uint64]uint64)
+	}
+	l.instance[a.Addr] = uint16(len(l.agents))
+}
+
+// Player adds a player to the agent table.
+func (l *Log) Player(addr uint64, prof evtc.ProfessionID, elite evtc.EliteSpecID, name, account string, subgroup int) {
+	l.addAgent(agent{
+		Addr:    addr,
+		Prof:    uint32(prof),
+		IsElite: uint32(elite),
+	}, name+"\x00"+account+"\x00"+strconv.Itoa(subgroup))
+}
+
+// NPC adds an NPC to the agent table.
+func (l *Log) NPC(addr uint64, species uint16, name string) {
+	l.addAgent(agent{
+		Addr:    addr,
+		Prof:    uint32(species),
+		IsElite: 0xffffffff,
+	}, name)
+}
+
+// Minion adds an NPC controlled by master to the agent table.
+func (l *Log) Minion(addr uint64, species uint16, name string, master uint64) {
+	l.NPC(addr, species, name)
+	l.master[addr] = master
+}
+
+// Gadget adds a gadget to the agent table.
+func (l *Log) Gadget(addr uint64, id uint16, name string) {
+	l.addAgent(agent{
+		Addr:    addr,
+		Prof:    0xffff0000 | uint32(id),
+		IsElite: 0xffffffff,
+	}, name)
+}
+
+// Skill adds a skill to the skill table.
+func (l *Log) Skill(id uint32, name string) {
+	s := skill{ID: id}
+	copy(s.Name[:len(s.Name)-1], name)
+	l.skills = append(l.skills, s)
+}
+
+// Add appends records to the log. Instance IDs that are 0 are filled in
+// from the agent table.
+func (l *Log) Add(records ...Record) {
+	for _, r := range records {
+		if r.SrcInstID == 0 {
+			r.SrcInstID = l.instance[r.SrcAgent]
+		}
+		if r.DstInstID == 0 && r.IsStateChange == 0 {
+			r.DstInstID = l.instance[r.DstAgent]
+		}
+		if master, ok := l.master[r.SrcAgent]; ok && r.SrcMasterInstID == 0 {
+			r.SrcMasterInstID = l.instance[master]
+		}
+		l.records = append(l.records, r)
+	}
+}
+
+// StateChange adds a statechange record with the given src_agent,
+// dst_agent, and value.
+func (l *Log) StateChange(ms uint64, kind uint8, src, dst uint64, value int32) {
+	l.Add(Record{Time: ms, IsStateChange: kind, SrcAgent: src, DstAgent: dst, Value: value})
+}
+
+// Damage adds a direct damage record with the given result.
+func (l *Log) Damage(ms uint64, src, dst uint64, skillID uint32, damage int32, result uint8) {
+	l.Add(Record{Time: ms, SrcAgent: src, DstAgent: dst, SkillID: skillID, Value: damage, Result: result, Iff: 1})
+}
+
+// ConditionDamage adds a buff damage record.
+func (l *Log) ConditionDamage(ms uint64, src, dst uint64, skillID uint32, damage int32) {
+	l.Add(Record{Time: ms, SrcAgent: src, DstAgent: dst, SkillID: skillID, BuffDmg: damage, Buff: 1, Iff: 1})
+}
+
+// ApplyBuff adds a buff application of one stack.
+func (l *Log) ApplyBuff(ms uint64, src, dst uint64, buffID uint32, duration time.Duration, instance uint32, active bool) {
+	r := Record{Time: ms, SrcAgent: src, DstAgent: dst, SkillID: buffID, Value: int32(duration / time.Millisecond), Buff: 1, Pad61_64: instance}
+	if active {
+		r.IsShields = 1
+	}
+	l.Add(r)
+}
+
+// RemoveBuff adds a buff removal from dst. kind is 1 for all stacks, 2 for
+// a single stack, and 3 for a stack removed by arcdps.
+func (l *Log) RemoveBuff(ms uint64, src, dst uint64, buffID uint32, kind uint8, instance uint32) {
+	// src_agent is the agent that lost the buff
+	l.Add(Record{Time: ms, SrcAgent: dst, DstAgent: src, SkillID: buffID, Buff: 1, IsBuffRemove: kind, Pad61_64: instance})
+}
+
+// Activation adds a skill activation record of the given kind.
+func (l *Log) Activation(ms uint64, src uint64, skillID uint32, kind uint8, duration time.Duration) {
+	l.Add(Record{Time: ms, SrcAgent: src, SkillID: skillID, IsActivation: kind, Value: int32(duration / time.Millisecond)})
+}
+
+// Position adds a position update.
+func (l *Log) Position(ms uint64, src uint64, x, y, z float32) {
+	l.Add(Record{
+		Time:          ms,
+		IsStateChange: 19, // CBTS_POSITION
+		SrcAgent:      src,
+		DstAgent:      uint64(math.Float32bits(x)) | uint64(math.Float32bits(y))<<32,
+		Value:         int32(math.Float32bits(z)),
+	})
+}
+
+// Bytes returns the log in the revision 1 format.
+func (l *Log) Bytes() []byte {
+	var buf bytes.Buffer
+	write := func(data interface{}) {
+		if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+			panic(err)
+		}
+	}
+
+	h := header{Revision: 1, Boss: l.Boss}
+	copy(h.Magic[:], "EVTC")
+	copy(h.Date[:], "20240101")
+	write(h)
+	write(uint32(len(l.agents)))
+	write(l.agents)
+	write(uint32(len(l.skills)))
+	write(l.skills)
+	write(l.records)
+
+	return buf.Bytes()
+}
+
+// Parse parses the log, failing the test if it cannot be parsed.
+func (l *Log) Parse(t testing.TB, opts ...evtc.Option) *evtc.EventChain {
+	t.Helper()
+
+	chain, err := evtc.Parse(bytes.NewReader(l.Bytes()), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain
+}
+
+// At returns the local time of the given arcdps timestamp in a log without
+// a log start event.
+func At(ms uint64) time.Time {
+	return time.Time{}.Add(time.Duration(ms) * time.Millisecond)
+}
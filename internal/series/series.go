@@ -0,0 +1,52 @@
+// Package series holds the bookkeeping shared by the analysis packages that
+// keep a history of samples for each agent.
+package series
+
+import (
+	"time"
+
+	"github.com/BenLubar/evtc"
+)
+
+// Index holds one timeline per agent. The zero value is ready to use.
+type Index[T any] struct {
+	timelines map[*evtc.Agent]*T
+	order     []*T
+}
+
+// Get returns the timeline of a, or nil if it has none.
+func (x *Index[T]) Get(a *evtc.Agent) *T {
+	return x.timelines[a]
+}
+
+// GetOrAdd returns the timeline of a, calling create to make one if a does
+// not have one yet.
+func (x *Index[T]) GetOrAdd(a *evtc.Agent, create func() *T) *T {
+	if tl, ok := x.timelines[a]; ok {
+		return tl
+	}
+
+	if x.timelines == nil {
+		x.timelines = make(map[*evtc.Agent]*T)
+	}
+	tl := create()
+	x.timelines[a] = tl
+	x.order = append(x.order, tl)
+	return tl
+}
+
+// All returns every timeline, in the order they were added.
+func (x *Index[T]) All() []*T {
+	return append([]*T(nil), x.order...)
+}
+
+// Append adds s to the end of samples, which are in chronological order. If
+// the last sample is not older than s, s replaces it instead, so each time
+// has at most one sample.
+func Append[S any](samples []S, s S, at func(S) time.Time) []S {
+	if n := len(samples); n != 0 && !at(samples[n-1]).Before(at(s)) {
+		samples[n-1] = s
+		return samples
+	}
+	return append(samples, s)
+}
@@ -0,0 +1,49 @@
+package evtc
+
+// Option configures Parse, NewDecoder, and the functions built on them.
+type Option func(*options)
+
+type options struct {
+	unknownEvents  UnknownEventPolicy
+	allowTruncated bool
+}
+
+func makeOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// UnknownEventPolicy decides what happens to combat events with enum values
+// this package does not recognize, such as statechanges added by newer
+// versions of arcdps.
+type UnknownEventPolicy int
+
+const (
+	// SkipUnknownEvents drops unrecognized events. This is the default.
+	SkipUnknownEvents UnknownEventPolicy = iota
+	// SurfaceUnknownEvents returns unrecognized events as *UnknownEvent.
+	SurfaceUnknownEvents
+	// RejectUnknownEvents makes parsing fail at the first unrecognized
+	// event with an *UnknownEventError.
+	RejectUnknownEvents
+)
+
+// UnknownEvents sets the policy for unrecognized events.
+func UnknownEvents(policy UnknownEventPolicy) Option {
+	return func(o *options) {
+		o.unknownEvents = policy
+	}
+}
+
+// AllowTruncated makes parsing tolerate logs that end early, such as logs
+// from game crashes. Instead of failing, the events read so far are kept,
+// EventChain.Truncated is set, and a LogEndEvent is synthesized if the log
+// did not have one.
+func AllowTruncated() Option {
+	return func(o *options) {
+		o.allowTruncated = true
+	}
+}
@@ -48,6 +48,10 @@ func (id ProfessionID) String() string {
 }
 
 // EliteSpecID is the ID of a Guild Wars 2 elite specialization.
+//
+// The built-in table covers the elite specializations up to Visions of
+// Eternity. Names of elite specializations released later are only available
+// through EliteSpecResolver.
 type EliteSpecID int
 
 const (
@@ -69,6 +73,24 @@ const (
 	Spellbreaker EliteSpecID = 61
 	Firebrand    EliteSpecID = 62
 	Renegade     EliteSpecID = 63
+	Harbinger    EliteSpecID = 64
+	Willbender   EliteSpecID = 65
+	Virtuoso     EliteSpecID = 66
+	Catalyst     EliteSpecID = 67
+	Bladesworn   EliteSpecID = 68
+	Vindicator   EliteSpecID = 69
+	Mechanist    EliteSpecID = 70
+	Specter      EliteSpecID = 71
+	Untamed      EliteSpecID = 72
+	Troubadour   EliteSpecID = 73
+	Paragon      EliteSpecID = 74
+	Amalgam      EliteSpecID = 75
+	Ritualist    EliteSpecID = 76
+	Antiquary    EliteSpecID = 77
+	Galeshot     EliteSpecID = 78
+	Conduit      EliteSpecID = 79
+	Evoker       EliteSpecID = 80
+	Luminary     EliteSpecID = 81
 )
 
 var hotEliteSpec = map[ProfessionID]EliteSpecID{
@@ -102,38 +124,159 @@ var eliteSpecName = map[EliteSpecID]string{
 	Spellbreaker: "Spellbreaker",
 	Firebrand:    "Firebrand",
 	Renegade:     "Renegade",
+	Harbinger:    "Harbinger",
+	Willbender:   "Willbender",
+	Virtuoso:     "Virtuoso",
+	Catalyst:     "Catalyst",
+	Bladesworn:   "Bladesworn",
+	Vindicator:   "Vindicator",
+	Mechanist:    "Mechanist",
+	Specter:      "Specter",
+	Untamed:      "Untamed",
+	Troubadour:   "Troubadour",
+	Paragon:      "Paragon",
+	Amalgam:      "Amalgam",
+	Ritualist:    "Ritualist",
+	Antiquary:    "Antiquary",
+	Galeshot:     "Galeshot",
+	Conduit:      "Conduit",
+	Evoker:       "Evoker",
+	Luminary:     "Luminary",
 }
 
-var apiEliteSpecName map[EliteSpecID]string
+var eliteSpecProfession = map[EliteSpecID]ProfessionID{
+	Druid:        Ranger,
+	Daredevil:    Thief,
+	Berserker:    Warrior,
+	Dragonhunter: Guardian,
+	Reaper:       Necromancer,
+	Chronomancer: Mesmer,
+	Scrapper:     Engineer,
+	Tempest:      Elementalist,
+	Herald:       Revenant,
+	Soulbeast:    Ranger,
+	Weaver:       Elementalist,
+	Holosmith:    Engineer,
+	Deadeye:      Thief,
+	Mirage:       Mesmer,
+	Scourge:      Necromancer,
+	Spellbreaker: Warrior,
+	Firebrand:    Guardian,
+	Renegade:     Revenant,
+	Harbinger:    Necromancer,
+	Willbender:   Guardian,
+	Virtuoso:     Mesmer,
+	Catalyst:     Elementalist,
+	Bladesworn:   Warrior,
+	Vindicator:   Revenant,
+	Mechanist:    Engineer,
+	Specter:      Thief,
+	Untamed:      Ranger,
+	Troubadour:   Mesmer,
+	Paragon:      Warrior,
+	Amalgam:      Engineer,
+	Ritualist:    Necromancer,
+	Antiquary:    Thief,
+	Galeshot:     Ranger,
+	Conduit:      Revenant,
+	Evoker:       Elementalist,
+	Luminary:     Guardian,
+}
+
+// Profession returns the profession this elite specialization belongs to,
+// or 0 if it is not known.
+func (id EliteSpecID) Profession() ProfessionID {
+	return eliteSpecProfession[id]
+}
+
+// A SpecResolver looks up the names of elite specializations that are not
+// built into this package.
+type SpecResolver interface {
+	ResolveEliteSpec(id EliteSpecID) (name string, ok bool)
+}
+
+// EliteSpecResolver is consulted by EliteSpecID.String for elite
+// specializations that are not built into this package. It is nil by
+// default, so String never makes network requests unless a resolver is
+// installed. Set it before parsing any logs.
+var EliteSpecResolver SpecResolver
+
+// APISpecResolver looks up elite specializations using the Guild Wars 2 API.
+// Wrap it in a CachingSpecResolver to avoid making a request every time an
+// ID is printed.
+type APISpecResolver struct {
+	// Client is used to make requests. If it is nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+// ResolveEliteSpec implements SpecResolver.
+func (r APISpecResolver) ResolveEliteSpec(id EliteSpecID) (string, bool) {
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
 
-func populateAPIEliteSpecs() {
-	resp, err := http.Get("https://api.guildwars2.com/v2/specializations?ids=all&lang=en&v=2019-06-17")
+	resp, err := client.Get("https://api.guildwars2.com/v2/specializations/" + strconv.Itoa(int(id)) + "?lang=en&v=2019-06-17")
 	if err != nil {
-		// can't do anything about it
-		return
+		return "", false
 	}
 	defer resp.Body.Close()
 
-	var specs []struct {
-		ID    int    `json:"id"`
+	if resp.StatusCode != http.StatusOK {
+		return "", false
+	}
+
+	var spec struct {
 		Name  string `json:"name"`
 		Elite bool   `json:"elite"`
 	}
-	if err = json.NewDecoder(resp.Body).Decode(&specs); err != nil {
-		// can't do anything about it
-		return
+	if err = json.NewDecoder(resp.Body).Decode(&spec); err != nil || !spec.Elite {
+		return "", false
 	}
 
-	apiEliteSpecName = make(map[EliteSpecID]string)
-	for _, s := range specs {
-		if s.Elite {
-			apiEliteSpecName[EliteSpecID(s.ID)] = s.Name
-		}
+	return spec.Name, true
+}
+
+// CachingSpecResolver remembers the answers of another SpecResolver,
+// including failures, so each ID is only looked up once.
+type CachingSpecResolver struct {
+	resolver SpecResolver
+
+	lock  sync.Mutex
+	cache map[EliteSpecID]string
+}
+
+// NewCachingSpecResolver returns a SpecResolver that caches the answers of
+// resolver.
+func NewCachingSpecResolver(resolver SpecResolver) *CachingSpecResolver {
+	return &CachingSpecResolver{
+		resolver: resolver,
+		cache:    make(map[EliteSpecID]string),
 	}
 }
 
-var populateAPIEliteSpecsOnce sync.Once
+// ResolveEliteSpec implements SpecResolver.
+func (r *CachingSpecResolver) ResolveEliteSpec(id EliteSpecID) (string, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 
+	if name, ok := r.cache[id]; ok {
+		return name, name != ""
+	}
+
+	name, ok := r.resolver.ResolveEliteSpec(id)
+	if !ok {
+		name = ""
+	}
+	r.cache[id] = name
+
+	return name, ok
+}
+
+// String returns the name of the elite specialization. IDs that are not in
+// the built-in table are looked up with EliteSpecResolver if it is set, and
+// are otherwise printed as a number.
 func (id EliteSpecID) String() string {
 	if id == 0 {
 		return ""
@@ -143,10 +286,10 @@ func (id EliteSpecID) String() string {
 		return name
 	}
 
-	populateAPIEliteSpecsOnce.Do(populateAPIEliteSpecs)
-
-	if name, ok := apiEliteSpecName[id]; ok {
-		return name
+	if r := EliteSpecResolver; r != nil {
+		if name, ok := r.ResolveEliteSpec(id); ok {
+			return name
+		}
 	}
 
 	return strconv.Itoa(int(id))
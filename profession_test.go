@@ -0,0 +1,43 @@
+package evtc
+
+import "testing"
+
+func TestEliteSpecTables(t *testing.T) {
+	if len(eliteSpecName) != len(eliteSpecProfession) {
+		t.Errorf("%d elite specialization names but %d professions", len(eliteSpecName), len(eliteSpecProfession))
+	}
+
+	// each profession has one elite specialization per expansion
+	count := make(map[ProfessionID]int)
+	for id, name := range eliteSpecName {
+		prof := id.Profession()
+		if prof == 0 {
+			t.Errorf("%s (%d) has no profession", name, int(id))
+		}
+		count[prof]++
+	}
+	for prof, n := range count {
+		if n != count[Guardian] {
+			t.Errorf("%v has %d elite specializations; Guardian has %d", prof, n, count[Guardian])
+		}
+	}
+
+	for _, tt := range []struct {
+		id   EliteSpecID
+		name string
+		prof ProfessionID
+	}{
+		{Firebrand, "Firebrand", Guardian},
+		{Untamed, "Untamed", Ranger},
+		{Luminary, "Luminary", Guardian},
+		{Troubadour, "Troubadour", Mesmer},
+		{Conduit, "Conduit", Revenant},
+	} {
+		if got := tt.id.String(); got != tt.name {
+			t.Errorf("EliteSpecID(%d).String() = %q; want %q", int(tt.id), got, tt.name)
+		}
+		if got := tt.id.Profession(); got != tt.prof {
+			t.Errorf("%s.Profession() = %v; want %v", tt.name, got, tt.prof)
+		}
+	}
+}
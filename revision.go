@@ -0,0 +1,66 @@
+package evtc
+
+// revision reads the parts of an EVTC file whose layout depends on the
+// revision byte in the header. Agents, skills, and events are always
+// converted to the newest in-memory layout so the rest of the package does
+// not need to know which revision a log was written in.
+type revision interface {
+	readAgents(r *offsetReader) ([]agent, error)
+	readSkills(r *offsetReader) ([]skill, error)
+	readEvent(r *offsetReader) (cbtevent1, error)
+}
+
+// revisions maps header revision numbers to their layouts. A newer arcdps
+// layout is supported by adding an entry here.
+var revisions = map[uint8]revision{
+	0: revision0{},
+	1: revision1{},
+}
+
+// tables reads the agent and skill tables in the layout shared by
+// revisions 0 and 1: a uint32 count followed by that many records.
+type tables struct{}
+
+func (tables) readAgents(r *offsetReader) ([]agent, error) {
+	var count uint32
+	if err := readRequiredSection(r, "agent count", &count); err != nil {
+		return nil, err
+	}
+	agents := make([]agent, count)
+	if err := readRequiredSection(r, "agents", agents); err != nil {
+		return nil, err
+	}
+	return agents, nil
+}
+
+func (tables) readSkills(r *offsetReader) ([]skill, error) {
+	var count uint32
+	if err := readRequiredSection(r, "skill count", &count); err != nil {
+		return nil, err
+	}
+	skills := make([]skill, count)
+	if err := readRequiredSection(r, "skills", skills); err != nil {
+		return nil, err
+	}
+	return skills, nil
+}
+
+type revision0 struct{ tables }
+
+func (revision0) readEvent(r *offsetReader) (cbtevent1, error) {
+	var event cbtevent0
+	if err := readSection(r, "events", &event); err != nil {
+		return cbtevent1{}, err
+	}
+	return convert0(event), nil
+}
+
+type revision1 struct{ tables }
+
+func (revision1) readEvent(r *offsetReader) (cbtevent1, error) {
+	var event cbtevent1
+	if err := readSection(r, "events", &event); err != nil {
+		return cbtevent1{}, err
+	}
+	return event, nil
+}
@@ -0,0 +1,109 @@
+package evtc
+
+import (
+	"math"
+	"time"
+)
+
+// SkillInfo is the definition of a skill, as recorded by arcdps.
+type SkillInfo struct {
+	ID   int
+	Name string
+
+	// Recharge, Range0, Range1, and TooltipTime are passed through from
+	// arcdps as-is.
+	Recharge    float32
+	Range0      float32
+	Range1      float32
+	TooltipTime float32
+
+	// Timings lists the actions that happen during the skill's cast, in
+	// the order they were recorded.
+	Timings []SkillTiming
+}
+
+// SkillTiming is an action that happens at a fixed time after a skill's
+// cast starts. The meaning of Action is game data that arcdps does not
+// document.
+type SkillTiming struct {
+	Action int
+	At     time.Duration
+}
+
+// SkillInfo returns the definition of the skill with the given ID. The
+// second return value is false if the log does not describe the skill,
+// which is always the case for logs from older versions of arcdps.
+func (c *EventChain) SkillInfo(id int) (SkillInfo, bool) {
+	info, ok := c.skillInfo[uint32(id)]
+	if !ok {
+		return SkillInfo{}, false
+	}
+
+	s := *info
+	s.Timings = append([]SkillTiming(nil), info.Timings...)
+	return s, true
+}
+
+// skillInfoFor returns the definition of a skill, adding it to the table if
+// it is not already there.
+func (c *EventChain) skillInfoFor(id uint32) *SkillInfo {
+	if info, ok := c.skillInfo[id]; ok {
+		return info
+	}
+
+	info := &SkillInfo{
+		ID:   int(id),
+		Name: c.skills[id],
+	}
+	if c.skillInfo == nil {
+		c.skillInfo = make(map[uint32]*SkillInfo)
+	}
+	c.skillInfo[id] = info
+	c.skillInfoIDs = append(c.skillInfoIDs, id)
+	return info
+}
+
+// CBTS_SKILLINFO, skillid = skillid, time through src_agent = float[4]
+// recharge, range0, range1, tooltiptime
+func parseSkillInfo(chain *EventChain, event cbtevent1) {
+	info := chain.skillInfoFor(event.SkillID)
+	info.Recharge = math.Float32frombits(uint32(event.Time))
+	info.Range0 = math.Float32frombits(uint32(event.Time >> 32))
+	info.Range1 = math.Float32frombits(uint32(event.SrcAgent))
+	info.TooltipTime = math.Float32frombits(uint32(event.SrcAgent >> 32))
+}
+
+// CBTS_SKILLTIMING, skillid = skillid, src_agent = action,
+// dst_agent = at millisecond
+func parseSkillTiming(chain *EventChain, event cbtevent1) {
+	info := chain.skillInfoFor(event.SkillID)
+	info.Timings = append(info.Timings, SkillTiming{
+		Action: int(event.SrcAgent),
+		At:     time.Duration(event.DstAgent) * time.Millisecond,
+	})
+}
+
+func encodeSkillInfo(info *SkillInfo, tick uint64) []cbtevent1 {
+	bits := func(v float32) uint64 {
+		return uint64(math.Float32bits(v))
+	}
+
+	events := []cbtevent1{{
+		Time:          bits(info.Recharge) | bits(info.Range0)<<32,
+		SrcAgent:      bits(info.Range1) | bits(info.TooltipTime)<<32,
+		SkillID:       uint32(info.ID),
+		IsStateChange: 32,
+	}}
+
+	for _, t := range info.Timings {
+		events = append(events, cbtevent1{
+			Time:          tick,
+			SrcAgent:      uint64(t.Action),
+			DstAgent:      uint64(t.At / time.Millisecond),
+			SkillID:       uint32(info.ID),
+			IsStateChange: 33,
+		})
+	}
+
+	return events
+}
@@ -0,0 +1,70 @@
+package evtc
+
+import (
+	"time"
+)
+
+// CommanderTag is a period during which a player displayed a commander tag.
+type CommanderTag struct {
+	Agent    *Agent
+	MarkerID int
+	Start    time.Time
+	// End is when the tag was removed or changed, or the time of the last
+	// event if the player still had the tag when the log ended.
+	End time.Time
+}
+
+// Contains reports whether the tag was displayed at t.
+func (t CommanderTag) Contains(at time.Time) bool {
+	return !at.Before(t.Start) && at.Before(t.End)
+}
+
+// Commanders returns every period during which a player displayed a
+// commander tag, in the order the tags appeared. Squad markers are left out.
+// Logs from arcdps versions that do not set TagEvent.IsCommander cannot tell
+// the two apart, so if no tag in the log is marked as a commander tag, every
+// marker held by a player is treated as one.
+func (c *EventChain) Commanders() []CommanderTag {
+	flagged := false
+	for _, e := range c.Events {
+		if te, ok := e.(*TagEvent); ok && te.IsCommander {
+			flagged = true
+			break
+		}
+	}
+
+	var tags []CommanderTag
+	current := make(map[*Agent]int)
+
+	var last time.Time
+	for _, e := range c.Events {
+		if local, _ := e.Time(); local.After(last) {
+			last = local
+		}
+
+		te, ok := e.(*TagEvent)
+		if !ok || te.Source == nil {
+			continue
+		}
+
+		if i, ok := current[te.Source]; ok {
+			tags[i].End = te.LocalTime
+			delete(current, te.Source)
+		}
+		_, isPlayer := te.Source.Player()
+		if te.MarkerID != 0 && (te.IsCommander || (!flagged && isPlayer)) {
+			current[te.Source] = len(tags)
+			tags = append(tags, CommanderTag{
+				Agent:    te.Source,
+				MarkerID: te.MarkerID,
+				Start:    te.LocalTime,
+			})
+		}
+	}
+
+	for _, i := range current {
+		tags[i].End = last
+	}
+
+	return tags
+}
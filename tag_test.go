@@ -0,0 +1,90 @@
+package evtc_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BenLubar/evtc"
+	"github.com/BenLubar/evtc/internal/evtctest"
+)
+
+const (
+	commander = 0x10
+	marked    = 0x11
+	boss      = 0x20
+)
+
+func tag(l *evtctest.Log, ms uint64, src uint64, marker int32, isCommander bool) {
+	r := evtctest.Record{Time: ms, IsStateChange: 37, SrcAgent: src, Value: marker} // CBTS_TAG
+	if isCommander {
+		r.Buff = 1
+	}
+	l.Add(r)
+}
+
+func TestCommanders(t *testing.T) {
+	type span struct {
+		agent      uint64
+		marker     int
+		start, end uint64
+	}
+
+	tests := []struct {
+		name  string
+		build func(l *evtctest.Log)
+		want  []span
+	}{
+		{
+			name: "squad markers are left out",
+			build: func(l *evtctest.Log) {
+				tag(l, 1000, commander, 5, true)
+				tag(l, 2000, marked, 6, false)
+				tag(l, 3000, commander, 0, false)
+			},
+			want: []span{{commander, 5, 1000, 3000}},
+		},
+		{
+			name: "tag changed",
+			build: func(l *evtctest.Log) {
+				tag(l, 1000, commander, 5, true)
+				tag(l, 3000, commander, 7, true)
+			},
+			want: []span{{commander, 5, 1000, 3000}, {commander, 7, 3000, 8000}},
+		},
+		{
+			name: "log without the commander flag",
+			build: func(l *evtctest.Log) {
+				tag(l, 1000, commander, 5, false)
+				tag(l, 2000, marked, 6, false)
+				tag(l, 2000, boss, 6, false)
+				tag(l, 4000, marked, 0, false)
+			},
+			want: []span{{commander, 5, 1000, 8000}, {marked, 6, 2000, 4000}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &evtctest.Log{}
+			l.Player(commander, evtc.Guardian, 0, "Commander", ":Commander.1234", 1)
+			l.Player(marked, evtc.Warrior, 0, "Marked", ":Marked.1234", 1)
+			l.NPC(boss, 15438, "Boss")
+			tt.build(l)
+			l.Damage(8000, commander, boss, 9143, 100, 0)
+			chain := l.Parse(t)
+
+			var got []span
+			for _, c := range chain.Commanders() {
+				got = append(got, span{
+					agent:  c.Agent.Address(),
+					marker: c.MarkerID,
+					start:  uint64(c.Start.Sub(evtctest.At(0)).Milliseconds()),
+					end:    uint64(c.End.Sub(evtctest.At(0)).Milliseconds()),
+				})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Commanders() = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package evtc
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var zipMagic = []byte{'P', 'K', 0x03, 0x04}
+
+// ParseAuto parses an EVTC file, transparently decompressing it first if it
+// is a zip archive (.zevtc or .evtc.zip). The archive must contain exactly
+// one file. See NewAutoDecoder.
+func ParseAuto(r io.Reader, opts ...Option) (*EventChain, error) {
+	d, err := NewAutoDecoder(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	return d.decodeAll()
+}
+
+// ParseFile opens and parses the named .evtc, .zevtc, or .evtc.zip file. A
+// zip archive must contain exactly one file.
+func ParseFile(name string, opts ...Option) (*EventChain, error) {
+	d, err := OpenFile(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	return d.decodeAll()
+}
+
+// NewAutoDecoder is like NewDecoder, but transparently decompresses r first
+// if it is a zip archive (.zevtc or .evtc.zip). As with OpenFile, the archive
+// must contain exactly one file. The archive is decompressed as it is read,
+// so an archive with more files is only rejected once the first file has
+// been read to the end. Call Close when done with the Decoder.
+func NewAutoDecoder(r io.Reader, opts ...Option) (*Decoder, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(zipMagic)); !bytes.Equal(magic, zipMagic) {
+		return NewDecoder(br, opts...)
+	}
+
+	rc, err := openZipStream(br)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := NewDecoder(bufio.NewReader(rc), opts...)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	d.closer = rc
+	return d, nil
+}
+
+// OpenFile opens the named .evtc, .zevtc, or .evtc.zip file and returns a
+// Decoder for it. A zip archive must contain exactly one file. Call Close
+// when done with the Decoder.
+func OpenFile(name string, opts ...Option) (*Decoder, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "evtc: could not open file")
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "evtc: could not open file")
+	}
+
+	var r io.Reader = f
+	closer := io.Closer(f)
+
+	magic := make([]byte, len(zipMagic))
+	if n, _ := f.ReadAt(magic, 0); n == len(magic) && bytes.Equal(magic, zipMagic) {
+		rc, err := openZip(f, fi.Size())
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		r, closer = rc, multiCloser{rc, f}
+	}
+
+	d, err := NewDecoder(bufio.NewReader(r), opts...)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	d.closer = closer
+	return d, nil
+}
+
+func openZip(r io.ReaderAt, size int64) (io.ReadCloser, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.Wrap(err, "evtc: could not read zip archive")
+	}
+
+	var files []*zip.File
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() {
+			files = append(files, f)
+		}
+	}
+	if len(files) != 1 {
+		return nil, errors.Errorf("evtc: expected exactly one file in zip archive, found %d", len(files))
+	}
+
+	rc, err := files[0].Open()
+	if err != nil {
+		return nil, errors.Wrap(err, "evtc: could not open file in zip archive")
+	}
+	return rc, nil
+}
+
+// zipLocalHeader is the header that precedes each file in a zip archive.
+type zipLocalHeader struct {
+	Signature        uint32
+	Version          uint16
+	Flags            uint16
+	Method           uint16
+	ModTime          uint16
+	ModDate          uint16
+	CRC32            uint32
+	CompressedSize   uint32
+	UncompressedSize uint32
+	NameLength       uint16
+	ExtraLength      uint16
+}
+
+var (
+	zipCentralMagic    = []byte{'P', 'K', 0x01, 0x02}
+	zipEndMagic        = []byte{'P', 'K', 0x05, 0x06}
+	zip64EndMagic      = []byte{'P', 'K', 0x06, 0x06}
+	zipDescriptorMagic = []byte{'P', 'K', 0x07, 0x08}
+)
+
+// zipEntry is a file in a zip archive that is being read as a stream.
+type zipEntry struct {
+	zipLocalHeader
+	name  string
+	zip64 bool
+}
+
+func (e *zipEntry) isDir() bool {
+	return strings.HasSuffix(e.name, "/")
+}
+
+// hasDescriptor reports whether the sizes and checksum of the entry follow
+// its data instead of being in its header.
+func (e *zipEntry) hasDescriptor() bool {
+	return e.Flags&0x8 != 0
+}
+
+// readZipEntry reads the local header of the next entry in the zip archive
+// read from r.
+func readZipEntry(r io.Reader) (*zipEntry, error) {
+	var e zipEntry
+	if err := binary.Read(r, binary.LittleEndian, &e.zipLocalHeader); err != nil {
+		return nil, errors.Wrap(err, "evtc: could not read zip archive")
+	}
+
+	name := make([]byte, e.NameLength)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return nil, errors.Wrap(err, "evtc: could not read zip archive")
+	}
+	e.name = string(name)
+
+	extra := make([]byte, e.ExtraLength)
+	if _, err := io.ReadFull(r, extra); err != nil {
+		return nil, errors.Wrap(err, "evtc: could not read zip archive")
+	}
+	for len(extra) >= 4 {
+		id, size := binary.LittleEndian.Uint16(extra), int(binary.LittleEndian.Uint16(extra[2:]))
+		if id == 0x0001 { // zip64 extended information
+			e.zip64 = true
+		}
+		if size > len(extra)-4 {
+			break
+		}
+		extra = extra[4+size:]
+	}
+
+	return &e, nil
+}
+
+// open returns the contents of the entry. The data must be read to the end
+// before the next entry in the archive can be read.
+func (e *zipEntry) open(r *bufio.Reader) (io.ReadCloser, error) {
+	switch e.Method {
+	case zip.Deflate:
+		return flate.NewReader(r), nil
+	case zip.Store:
+		// the size is only known up front if it is not in a data
+		// descriptor or a zip64 extra field
+		if e.hasDescriptor() || e.CompressedSize == 0xffffffff {
+			return nil, errors.New("evtc: cannot stream uncompressed zip entry of unknown size")
+		}
+		return ioutil.NopCloser(io.LimitReader(r, int64(e.CompressedSize))), nil
+	default:
+		return nil, errors.Errorf("evtc: unsupported zip compression method %d", e.Method)
+	}
+}
+
+// skipDescriptor skips the data descriptor that follows the data of the
+// entry, if it has one.
+func (e *zipEntry) skipDescriptor(r *bufio.Reader) error {
+	if !e.hasDescriptor() {
+		return nil
+	}
+
+	// crc32 followed by the compressed and uncompressed sizes, optionally
+	// preceded by a signature
+	size := 12
+	if e.zip64 {
+		size = 20
+	}
+	if sig, _ := r.Peek(len(zipDescriptorMagic)); bytes.Equal(sig, zipDescriptorMagic) {
+		size += len(zipDescriptorMagic)
+	}
+	if _, err := r.Discard(size); err != nil {
+		return errors.Wrap(err, "evtc: could not read zip archive")
+	}
+	return nil
+}
+
+// skip reads past the data of the entry.
+func (e *zipEntry) skip(r *bufio.Reader) error {
+	rc, err := e.open(r)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if _, err := io.Copy(ioutil.Discard, rc); err != nil {
+		return errors.Wrap(err, "evtc: could not read zip archive")
+	}
+	return e.skipDescriptor(r)
+}
+
+// nextZipEntry reads the header of the next file in the zip archive read
+// from r, skipping directories. It returns nil once the central directory
+// is reached.
+func nextZipEntry(r *bufio.Reader) (*zipEntry, error) {
+	for {
+		sig, err := r.Peek(len(zipMagic))
+		switch {
+		case err == io.EOF && len(sig) == 0:
+			return nil, nil
+		case bytes.Equal(sig, zipCentralMagic), bytes.Equal(sig, zipEndMagic), bytes.Equal(sig, zip64EndMagic):
+			return nil, nil
+		case !bytes.Equal(sig, zipMagic):
+			return nil, errors.New("evtc: could not read zip archive: unexpected data between entries")
+		}
+
+		e, err := readZipEntry(r)
+		if err != nil {
+			return nil, err
+		}
+		if !e.isDir() {
+			return e, nil
+		}
+		if err := e.skip(r); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// zipStream reads the only file in a zip archive without seeking to the
+// central directory at the end of the archive. Once the file has been read,
+// it checks that no other file follows it.
+type zipStream struct {
+	r     *bufio.Reader
+	entry *zipEntry
+	data  io.ReadCloser
+
+	done bool
+	err  error
+}
+
+// openZipStream returns the contents of the file in the zip archive read
+// from r. Reading it fails if the archive contains more than one file.
+func openZipStream(r *bufio.Reader) (io.ReadCloser, error) {
+	e, err := nextZipEntry(r)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, errors.New("evtc: expected exactly one file in zip archive, found 0")
+	}
+
+	data, err := e.open(r)
+	if err != nil {
+		return nil, err
+	}
+	return &zipStream{r: r, entry: e, data: data}, nil
+}
+
+func (z *zipStream) Read(p []byte) (int, error) {
+	if z.done {
+		return 0, z.err
+	}
+
+	n, err := z.data.Read(p)
+	if err == io.EOF {
+		z.done, z.err = true, z.checkRest()
+		err = z.err
+	}
+	return n, err
+}
+
+// checkRest returns io.EOF if the rest of the archive holds no more files.
+func (z *zipStream) checkRest() error {
+	if err := z.entry.skipDescriptor(z.r); err != nil {
+		return err
+	}
+
+	e, err := nextZipEntry(z.r)
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		return errors.New("evtc: expected exactly one file in zip archive, found more")
+	}
+	return io.EOF
+}
+
+func (z *zipStream) Close() error {
+	return z.data.Close()
+}
+
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var first error
+	for _, c := range m {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
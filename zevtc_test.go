@@ -0,0 +1,87 @@
+package evtc
+
+import (
+	"archive/zip"
+	"bytes"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestZipArchive(t *testing.T) {
+	log := writeTestLog(t, 1)
+
+	tests := []struct {
+		name   string
+		files  []string
+		method uint16
+		ok     bool
+	}{
+		{"one file", []string{"a.evtc"}, zip.Deflate, true},
+		{"stored file", []string{"a.evtc"}, zip.Store, true},
+		{"directory", []string{"logs/", "logs/a.evtc"}, zip.Deflate, true},
+		{"two files", []string{"a.evtc", "b.evtc"}, zip.Deflate, false},
+		{"no files", []string{"logs/"}, zip.Deflate, false},
+	}
+
+	dir, err := ioutil.TempDir("", "evtc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			for _, name := range tt.files {
+				h := &zip.FileHeader{Name: name, Method: tt.method}
+				var data []byte
+				if name[len(name)-1] != '/' {
+					data = log
+				}
+
+				var w io.Writer
+				var err error
+				if tt.method == zip.Store && data != nil {
+					// a stored file can only be streamed if its
+					// size is in the local header
+					h.CRC32 = crc32.ChecksumIEEE(data)
+					h.CompressedSize64 = uint64(len(data))
+					h.UncompressedSize64 = uint64(len(data))
+					w, err = zw.CreateRaw(h)
+				} else {
+					w, err = zw.CreateHeader(h)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := w.Write(data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := ParseAuto(bytes.NewReader(buf.Bytes()))
+			if (err == nil) != tt.ok {
+				t.Errorf("ParseAuto: err = %v; want ok = %v", err, tt.ok)
+
+			}
+
+			name := filepath.Join(dir, strconv.Itoa(i)+".zevtc")
+			if err := ioutil.WriteFile(name, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err = ParseFile(name)
+			if (err == nil) != tt.ok {
+				t.Errorf("ParseFile: err = %v; want ok = %v", err, tt.ok)
+			}
+		})
+	}
+}